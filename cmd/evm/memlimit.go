@@ -0,0 +1,158 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// memoryLimitPollInterval is how often the heap usage is sampled.
+const memoryLimitPollInterval = 100 * time.Millisecond
+
+// memoryLimitExceeded is the error reported for a subtest whose heap growth
+// exceeded the limit.
+type memoryLimitExceeded struct {
+	heap, limit uint64 // bytes
+}
+
+func (e *memoryLimitExceeded) Error() string {
+	return fmt.Sprintf("MemoryLimitExceeded: heap growth %d MB exceeds the limit of %d MB", e.heap>>20, e.limit>>20)
+}
+
+// memoryLimiter is an EVM logger aborting execution once the heap growth sampled
+// by watch exceeds a limit. The growth is measured from the heap allocated when
+// the limiter is created, so the heap left over by earlier subtests, garbage or
+// not, isn't held against it. The next step then cancels the EVM, which halts at
+// the next jump as if stopped, and err reports the subtest as failed. All events
+// are forwarded to an optional inner logger.
+type memoryLimiter struct {
+	inner    vm.EVMLogger
+	limit    uint64        // bytes
+	baseline uint64        // heap allocated when created, in bytes
+	exceeded atomic.Uint64 // sampled heap growth once over the limit, zero until then
+	quit     chan struct{}
+
+	env       *vm.EVM // EVM of the running transaction
+	cancelled bool    // set once env was cancelled over the limit
+}
+
+func newMemoryLimiter(inner vm.EVMLogger, limitMB uint64) *memoryLimiter {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return &memoryLimiter{inner: inner, limit: limitMB << 20, baseline: stats.HeapAlloc, quit: make(chan struct{})}
+}
+
+// watch samples the heap growth until stopped or the limit is exceeded.
+func (m *memoryLimiter) watch() {
+	ticker := time.NewTicker(memoryLimitPollInterval)
+	defer ticker.Stop()
+
+	var stats runtime.MemStats
+	for {
+		select {
+		case <-ticker.C:
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > m.baseline && stats.HeapAlloc-m.baseline > m.limit {
+				m.exceeded.Store(stats.HeapAlloc - m.baseline)
+				return
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// stop terminates watch.
+func (m *memoryLimiter) stop() {
+	close(m.quit)
+}
+
+// check cancels execution if the limit has been exceeded.
+func (m *memoryLimiter) check() {
+	if m.cancelled || m.env == nil || m.exceeded.Load() == 0 {
+		return
+	}
+	m.env.Cancel()
+	m.cancelled = true
+}
+
+// err returns a *memoryLimitExceeded if execution was cancelled over the limit.
+func (m *memoryLimiter) err() error {
+	if !m.cancelled {
+		return nil
+	}
+	return &memoryLimitExceeded{heap: m.exceeded.Load(), limit: m.limit}
+}
+
+func (m *memoryLimiter) CaptureTxStart(gasLimit uint64) {
+	if m.inner != nil {
+		m.inner.CaptureTxStart(gasLimit)
+	}
+}
+
+func (m *memoryLimiter) CaptureTxEnd(restGas uint64) {
+	if m.inner != nil {
+		m.inner.CaptureTxEnd(restGas)
+	}
+}
+
+func (m *memoryLimiter) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	m.env = env
+	m.check()
+	if m.inner != nil {
+		m.inner.CaptureStart(env, from, to, create, input, gas, value)
+	}
+}
+
+func (m *memoryLimiter) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if m.inner != nil {
+		m.inner.CaptureEnd(output, gasUsed, err)
+	}
+}
+
+func (m *memoryLimiter) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	m.check()
+	if m.inner != nil {
+		m.inner.CaptureEnter(typ, from, to, input, gas, value)
+	}
+}
+
+func (m *memoryLimiter) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if m.inner != nil {
+		m.inner.CaptureExit(output, gasUsed, err)
+	}
+}
+
+func (m *memoryLimiter) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	m.check()
+	if m.inner != nil {
+		m.inner.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (m *memoryLimiter) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if m.inner != nil {
+		m.inner.CaptureFault(pc, op, gas, cost, scope, depth, err)
+	}
+}
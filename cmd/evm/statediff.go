@@ -0,0 +1,93 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// preStateDump dumps the pre-state of the given state test.
+func preStateDump(test *tests.StateTest) state.Dump {
+	triedb, _, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.PreState(), false, rawdb.HashScheme)
+	defer triedb.Close()
+
+	return statedb.RawDump(nil)
+}
+
+// diffStateDump returns the accounts of post that differ from pre. Changed
+// accounts only carry their code if it changed, and only the storage slots
+// that changed, with cleared slots set to an empty value. Accounts deleted
+// from pre are included with a zero balance and no other fields.
+func diffStateDump(pre, post state.Dump) state.Dump {
+	diff := state.Dump{
+		Root:     post.Root,
+		Accounts: make(map[common.Address]state.DumpAccount),
+	}
+	for addr, acc := range post.Accounts {
+		old, ok := pre.Accounts[addr]
+		if !ok {
+			diff.Accounts[addr] = acc
+			continue
+		}
+		if acc.Balance == old.Balance && acc.Nonce == old.Nonce &&
+			bytes.Equal(acc.CodeHash, old.CodeHash) && bytes.Equal(acc.Root, old.Root) {
+			continue
+		}
+		changed := state.DumpAccount{
+			Balance:  acc.Balance,
+			Nonce:    acc.Nonce,
+			Root:     acc.Root,
+			CodeHash: acc.CodeHash,
+		}
+		if !bytes.Equal(acc.CodeHash, old.CodeHash) {
+			changed.Code = acc.Code
+		}
+		if !bytes.Equal(acc.Root, old.Root) {
+			changed.Storage = diffStorage(old.Storage, acc.Storage)
+		}
+		diff.Accounts[addr] = changed
+	}
+	for addr := range pre.Accounts {
+		if _, ok := post.Accounts[addr]; !ok {
+			diff.Accounts[addr] = state.DumpAccount{Balance: "0"}
+		}
+	}
+	return diff
+}
+
+// diffStorage returns the slots of post that differ from pre, along with the
+// slots of pre cleared in post, set to an empty value.
+func diffStorage(pre, post map[common.Hash]string) map[common.Hash]string {
+	diff := make(map[common.Hash]string, len(post))
+	for key, value := range post {
+		diff[key] = value
+	}
+	for key, value := range pre {
+		switch postValue, ok := post[key]; {
+		case !ok:
+			diff[key] = ""
+		case postValue == value:
+			delete(diff, key)
+		}
+	}
+	return diff
+}
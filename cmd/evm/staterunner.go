@@ -17,10 +17,24 @@
 package main
 
 import (
+	"archive/tar"
 	"bufio"
+	"compress/gzip"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -31,6 +45,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
 	"github.com/ethereum/go-ethereum/tests"
 	"github.com/urfave/cli/v2"
 )
@@ -42,6 +57,26 @@ var stateTestCommand = &cli.Command{
 	ArgsUsage: "<file>",
 	Flags: []cli.Flag{
 		stateTestForkFlag,
+		stateTestStdinJSONFlag,
+		stateTestFormatFlag,
+		stateTestCountStepsFlag,
+		stateTestDiffFormatFlag,
+		stateTestStreamFlag,
+		stateTestCPUProfileFlag,
+		stateTestMemProfileFlag,
+		stateTestExpectedFailsFlag,
+		stateTestStrictFlag,
+		stateTestQuietFlag,
+		stateTestListFlag,
+		stateTestExtraEipsFlag,
+		stateTestKeepGoingFlag,
+		stateTestDumpFirstFlag,
+		stateTestDumpMatchFlag,
+		stateTestRunFlag,
+		stateTestSkipUnknownForksFlag,
+		stateTestMaxMemoryFlag,
+		stateTestAbortOnFailFlag,
+		stateTestStateDiffFlag,
 		stateTestEVMCEWASMFlag,
 		utils.EVMInterpreterFlag,
 	},
@@ -60,6 +95,127 @@ var stateTestForkFlag = &cli.StringFlag{
 	Category: flags.DevCategory,
 }
 
+var stateTestStdinJSONFlag = &cli.BoolFlag{
+	Name:     "stdin-json",
+	Usage:    "Read the state tests themselves (rather than filenames) as JSON from standard input",
+	Category: flags.DevCategory,
+}
+
+var stateTestFormatFlag = &cli.StringFlag{
+	Name:     "format",
+	Usage:    "Output format of the results (json, table or csv)",
+	Value:    "json",
+	Category: flags.DevCategory,
+}
+
+var stateTestCountStepsFlag = &cli.BoolFlag{
+	Name:     "count-steps",
+	Usage:    "Report the number of executed opcodes of each subtest",
+	Category: flags.DevCategory,
+}
+
+var stateTestDiffFormatFlag = &cli.BoolFlag{
+	Name:     "diff-format",
+	Usage:    "Emit one 'name fork index root pass' line per subtest, sorted across all inputs, for diffing against other clients",
+	Category: flags.DevCategory,
+}
+
+var stateTestStreamFlag = &cli.BoolFlag{
+	Name:     "stream",
+	Usage:    "Emit each result as a newline-delimited JSON object as soon as it completes",
+	Category: flags.DevCategory,
+}
+
+var stateTestCPUProfileFlag = &cli.StringFlag{
+	Name:     "cpuprofile",
+	Usage:    "Write a CPU profile of the test run to the given file",
+	Category: flags.DevCategory,
+}
+
+var stateTestMemProfileFlag = &cli.StringFlag{
+	Name:     "memprofile",
+	Usage:    "Write a heap profile to the given file after the test run",
+	Category: flags.DevCategory,
+}
+
+var stateTestExpectedFailsFlag = &cli.StringFlag{
+	Name:     "expected-fails",
+	Usage:    "File listing name/fork/index subtests permitted to fail; exit non-zero on any other failure or if a listed subtest passes",
+	Category: flags.DevCategory,
+}
+
+var stateTestStrictFlag = &cli.BoolFlag{
+	Name:     "strict",
+	Usage:    "Fail if an account of the --prestate alloc is also defined by a test",
+	Category: flags.DevCategory,
+}
+
+var stateTestQuietFlag = &cli.BoolFlag{
+	Name:     "quiet",
+	Usage:    "Only print a one-line summary of each failing subtest",
+	Category: flags.DevCategory,
+}
+
+var stateTestListFlag = &cli.BoolFlag{
+	Name:     "list",
+	Usage:    "List the names, forks and indices of the subtests without running them",
+	Category: flags.DevCategory,
+}
+
+var stateTestExtraEipsFlag = &cli.IntSliceFlag{
+	Name:     "eips",
+	Usage:    "Additional EIPs to enable on top of those of the fork, e.g. --eips 3855,3860",
+	Category: flags.DevCategory,
+}
+
+var stateTestKeepGoingFlag = &cli.BoolFlag{
+	Name:     "keep-going",
+	Usage:    "Report files that fail to load as failed results and continue with the next file in batch mode",
+	Category: flags.DevCategory,
+}
+
+var stateTestDumpFirstFlag = &cli.IntFlag{
+	Name:     "dump-first",
+	Usage:    "Only dump the state of the first N failing subtests (implies --dump)",
+	Category: flags.DevCategory,
+}
+
+var stateTestDumpMatchFlag = &cli.StringFlag{
+	Name:     "dump-match",
+	Usage:    "Only dump the state of subtests of the test with the given name (implies --dump)",
+	Category: flags.DevCategory,
+}
+
+var stateTestRunFlag = &cli.StringFlag{
+	Name:     "run",
+	Usage:    "Only run tests whose name matches the given regular expression",
+	Category: flags.DevCategory,
+}
+
+var stateTestSkipUnknownForksFlag = &cli.BoolFlag{
+	Name:     "skip-unknown-forks",
+	Usage:    "Report subtests of forks unsupported by this binary as skipped instead of failed",
+	Category: flags.DevCategory,
+}
+
+var stateTestMaxMemoryFlag = &cli.Uint64Flag{
+	Name:     "max-memory",
+	Usage:    "Abort subtests once the heap grows by more than the given number of MB while they run, reporting them as failed",
+	Category: flags.DevCategory,
+}
+
+var stateTestAbortOnFailFlag = &cli.BoolFlag{
+	Name:     "abort-on-fail",
+	Usage:    "Stop at the first failing subtest, reporting only it as a JSON result with a state dump and a trace on stderr. Takes precedence over --quiet, --stream and --format, whose output is held back until the run completes",
+	Category: flags.DevCategory,
+}
+
+var stateTestStateDiffFlag = &cli.BoolFlag{
+	Name:     "state-diff",
+	Usage:    "Dump only the accounts and storage slots changed from the pre-state, for failing subtests and any dumped with --dump",
+	Category: flags.DevCategory,
+}
+
 // StatetestResult contains the execution status after running a state test, any
 // error that might have occurred and a dump of the final state if requested.
 type StatetestResult struct {
@@ -69,9 +225,51 @@ type StatetestResult struct {
 	Fork  string       `json:"fork"`
 	Error string       `json:"error,omitempty"`
 	State *state.Dump  `json:"state,omitempty"`
+
+	Index int `json:"-"` // Subtest index, reported in the csv and diff formats
+
+	GasUsed uint64 `json:"gasUsed,omitempty"` // Only set for applied messages
+	Steps   uint64 `json:"steps,omitempty"`   // Only set with --count-steps
+
+	BlobGasUsed uint64 `json:"blobGasUsed,omitempty"` // Only set for applied blob transactions
+
+	ExpectedFail bool `json:"expectedFail,omitempty"` // Listed in the --expected-fails file
+
+	Eips []int `json:"eips,omitempty"` // Additional EIPs enabled with --eips
+
+	Skipped bool `json:"skipped,omitempty"` // Fork unsupported by this binary, with --skip-unknown-forks
+
+	Elapsed time.Duration `json:"-"` // Wall time of the execution, reported in the csv format
 }
 
 func stateTestCmd(ctx *cli.Context) error {
+	// Set up profiling, deferring the teardown so profiles are flushed even if
+	// the run is aborted by an error
+	if path := ctx.String(stateTestCPUProfileFlag.Name); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("could not create CPU profile: %v", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return fmt.Errorf("could not start CPU profile: %v", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+	if path := ctx.String(stateTestMemProfileFlag.Name); path != "" {
+		defer func() {
+			f, err := os.Create(path)
+			if err != nil {
+				log.Error("Could not create memory profile", "err", err)
+				return
+			}
+			defer f.Close()
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				log.Error("Could not write memory profile", "err", err)
+			}
+		}()
+	}
 	// Configure the EVM logger
 	config := &logger.Config{
 		EnableMemory:     !ctx.Bool(DisableMemoryFlag.Name),
@@ -88,6 +286,11 @@ func stateTestCmd(ctx *cli.Context) error {
 		cfg.Tracer = logger.NewStructLogger(config)
 	}
 
+	cfg.ExtraEips = ctx.IntSlice(stateTestExtraEipsFlag.Name)
+	if len(cfg.ExtraEips) > 0 {
+		log.Info("Running tests with additional EIPs", "eips", cfg.ExtraEips)
+	}
+
 	cfg.EWASMInterpreter = ctx.String(stateTestEVMCEWASMFlag.Name)
 	cfg.EVMInterpreter = ctx.String(utils.EVMInterpreterFlag.Name)
 
@@ -100,9 +303,105 @@ func stateTestCmd(ctx *cli.Context) error {
 		vm.InitEVMCEwasm(cfg.EWASMInterpreter)
 	}
 
+	opts := &stateTestOptions{
+		jsonOut: ctx.Bool(MachineFlag.Name),
+		dump:    ctx.Bool(DumpFlag.Name),
+		fork:    ctx.String(stateTestForkFlag.Name),
+		format:  ctx.String(stateTestFormatFlag.Name),
+
+		countSteps: ctx.Bool(stateTestCountStepsFlag.Name),
+		stream:     ctx.Bool(stateTestStreamFlag.Name),
+		quiet:      ctx.Bool(stateTestQuietFlag.Name),
+		list:       ctx.Bool(stateTestListFlag.Name),
+		keepGoing:  ctx.Bool(stateTestKeepGoingFlag.Name),
+
+		skipUnknownForks: ctx.Bool(stateTestSkipUnknownForksFlag.Name),
+		maxMemory:        ctx.Uint64(stateTestMaxMemoryFlag.Name),
+		abortOnFail:      ctx.Bool(stateTestAbortOnFailFlag.Name),
+		stateDiff:        ctx.Bool(stateTestStateDiffFlag.Name),
+
+		dumpFirst: ctx.Int(stateTestDumpFirstFlag.Name),
+		dumpMatch: ctx.String(stateTestDumpMatchFlag.Name),
+	}
+	if opts.dumpFirst > 0 || opts.dumpMatch != "" {
+		opts.dump = true
+	}
+	if expr := ctx.String(stateTestRunFlag.Name); expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid --%s expression: %v", stateTestRunFlag.Name, err)
+		}
+		opts.run = re
+	}
+	if ctx.Bool(stateTestDiffFormatFlag.Name) {
+		opts.format = "diff"
+	}
+	switch opts.format {
+	case "json", "table", "csv", "diff":
+	default:
+		return fmt.Errorf("unknown output format %q, want json, table, csv or diff", opts.format)
+	}
+	if path := ctx.String(GenesisFlag.Name); path != "" {
+		opts.prestate = readGenesis(path).Alloc
+		opts.strict = ctx.Bool(stateTestStrictFlag.Name)
+	}
+	if path := ctx.String(stateTestExpectedFailsFlag.Name); path != "" {
+		expected, err := loadExpectedFailures(path)
+		if err != nil {
+			return err
+		}
+		opts.expected = expected
+	}
+	err := runStateTestInputs(ctx, cfg, opts)
+
+	// Print the results of all inputs at once in the formats aggregating them,
+	// so batch runs produce a single document. A run halted by --abort-on-fail
+	// has already reported its failure on its own.
+	if opts.aggregate() && !errors.Is(err, errStateTestAborted) {
+		opts.printResults(os.Stdout, opts.results)
+	}
+	if err != nil {
+		return err
+	}
+	// The expected-failure list, if given, decides which failures are fatal
+	if opts.expected != nil {
+		if err := opts.expected.err(); err != nil {
+			return err
+		}
+	} else if opts.failed > 0 {
+		return fmt.Errorf("%d state tests failed", opts.failed)
+	}
+	if opts.loadFailed > 0 {
+		return fmt.Errorf("%d state test files failed to load", opts.loadFailed)
+	}
+	return nil
+}
+
+// errStateTestAborted is returned when a run is halted by --abort-on-fail.
+var errStateTestAborted = errors.New("aborted on first failure")
+
+// runStateTestInputs runs the state tests from the input selected on the
+// command line: JSON on stdin, a single file, or filenames read from stdin.
+func runStateTestInputs(ctx *cli.Context, cfg vm.Config, opts *stateTestOptions) error {
+	// Decode test content piped directly through stdin, one or more
+	// concatenated (or newline-delimited) JSON objects
+	if ctx.Bool(stateTestStdinJSONFlag.Name) {
+		dec := json.NewDecoder(os.Stdin)
+		for {
+			var stateTests map[string]tests.StateTest
+			if err := dec.Decode(&stateTests); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if err := runStateTests(stateTests, cfg, opts); err != nil {
+				return err
+			}
+		}
+	}
 	// Load the test content from the input file
 	if len(ctx.Args().First()) != 0 {
-		return runStateTest(ctx.Args().First(), cfg, ctx.Bool(MachineFlag.Name), ctx.Bool(DumpFlag.Name), ctx.String(stateTestForkFlag.Name))
+		return runStateTest(ctx.Args().First(), cfg, opts)
 	}
 	// Read filenames from stdin and execute back-to-back
 	scanner := bufio.NewScanner(os.Stdin)
@@ -111,54 +410,510 @@ func stateTestCmd(ctx *cli.Context) error {
 		if len(fname) == 0 {
 			return nil
 		}
-		if err := runStateTest(fname, cfg, ctx.Bool(MachineFlag.Name), ctx.Bool(DumpFlag.Name), ctx.String(stateTestForkFlag.Name)); err != nil {
-			return err
+		if err := runStateTest(fname, cfg, opts); err != nil {
+			if !opts.keepGoing || errors.Is(err, errStateTestAborted) {
+				return err
+			}
+			reportStateTestLoadFailure(fname, err, opts)
 		}
 	}
 	return nil
 }
 
+// reportStateTestLoadFailure reports a state test file that failed to load as
+// a failed result named after the file, in the output format of the results of
+// the tests that ran. The failure is logged on stderr too.
+func reportStateTestLoadFailure(fname string, err error, opts *stateTestOptions) {
+	opts.loadFailed++
+	log.Error("Failed to load state test file", "file", fname, "err", err)
+
+	result := StatetestResult{Name: fname, Pass: false, Error: err.Error()}
+	if opts.aggregate() {
+		opts.results = append(opts.results, result)
+		return
+	}
+	opts.printResults(os.Stdout, []StatetestResult{result})
+}
+
+// stateTestOptions holds the output and filtering settings of a statetest run.
+type stateTestOptions struct {
+	jsonOut bool           // Emit intermediate state roots to stderr
+	dump    bool           // Include a dump of the post state in the results
+	fork    string         // Only run subtests of this fork, if set
+	run     *regexp.Regexp // Only run tests with a matching name, if set
+	format  string         // Output format of the results: json, table, csv or diff
+
+	countSteps bool // Attach a step counter reporting the executed opcodes
+	stream     bool // Print each result as a JSON line when ready instead of aggregating
+	quiet      bool // Only print a summary line per failing subtest
+	list       bool // List the subtests instead of running them
+	keepGoing  bool // Report files failing to load and continue with the next one
+
+	skipUnknownForks bool   // Report subtests of unsupported forks as skipped instead of failed
+	maxMemory        uint64 // Abort subtests once the heap grows by this many MB, if non-zero
+	abortOnFail      bool   // Halt at the first failing subtest, reporting it in full
+	stateDiff        bool   // Dump the changes from the pre-state, of failing subtests by default
+
+	dumpFirst int    // Only dump the state of this many failing subtests, if non-zero
+	dumpMatch string // Only dump the state of subtests of this test, if set
+	dumped    int    // Number of state dumps emitted so far
+
+	failed     int // Number of failed subtests across all inputs
+	loadFailed int // Number of input files that failed to load
+
+	results []StatetestResult // Results of all inputs, printed once the run completes if aggregated
+
+	expected *expectedFailures // Subtests permitted to fail, if set
+
+	prestate genesisT.GenesisAlloc // Accounts merged into the pre-state of every test
+	strict   bool                  // Fail on accounts defined by both the prestate and a test
+}
+
+// aggregate reports whether the results of all inputs are collected and printed
+// together once the run completes. The default json format prints a document
+// per input instead, as they are run, and --quiet and --stream print each
+// result when ready. With --abort-on-fail, results are always held back, so a
+// halted run reports nothing but the failure.
+func (opts *stateTestOptions) aggregate() bool {
+	if opts.list {
+		return false
+	}
+	return opts.abortOnFail || (opts.format != "json" && !opts.stream && !opts.quiet)
+}
+
+// printResults writes the results to w as summary lines of the failures with
+// --quiet, as JSON lines with --stream, or in the selected format otherwise.
+// Results of files failing to load are identified by the file name alone.
+func (opts *stateTestOptions) printResults(w io.Writer, results []StatetestResult) {
+	switch {
+	case opts.quiet:
+		for _, r := range results {
+			id := fmt.Sprintf("%s/%s/%d", r.Name, r.Fork, r.Index)
+			if r.Fork == "" {
+				id = r.Name
+			}
+			switch {
+			case r.Skipped:
+				fmt.Fprintf(w, "SKIP %s: %s\n", id, r.Error)
+			case !r.Pass:
+				fmt.Fprintf(w, "FAIL %s: %s\n", id, r.Error)
+			}
+		}
+	case opts.stream:
+		for _, r := range results {
+			out, _ := json.Marshal(r)
+			fmt.Fprintln(w, string(out))
+		}
+	default:
+		printStateTestResults(w, results, opts.format)
+	}
+}
+
+// shouldDump reports whether the post state of the given result is to be
+// dumped, honouring the --dump-first and --dump-match restrictions.
+func (opts *stateTestOptions) shouldDump(result *StatetestResult) bool {
+	// The failure halting the run is dumped regardless of the other settings
+	if opts.abortOnFail && !result.Pass {
+		return true
+	}
+	// On its own, --state-diff dumps the changes of every failing subtest
+	if !opts.dump {
+		return opts.stateDiff && !result.Pass
+	}
+	if opts.dumpMatch != "" && result.Name != opts.dumpMatch {
+		return false
+	}
+	if opts.dumpFirst > 0 && (result.Pass || opts.dumped >= opts.dumpFirst) {
+		return false
+	}
+	return true
+}
+
 // runStateTest loads the state-test given by fname, and executes the test.
-func runStateTest(fname string, cfg vm.Config, jsonOut, dump bool, testFork string) error {
+// Tar archives, optionally gzipped, are read with runStateTestArchive.
+func runStateTest(fname string, cfg vm.Config, opts *stateTestOptions) error {
+	if strings.HasSuffix(fname, ".tar") || strings.HasSuffix(fname, ".tar.gz") || strings.HasSuffix(fname, ".tgz") {
+		return runStateTestArchive(fname, cfg, opts)
+	}
 	src, err := os.ReadFile(fname)
 	if err != nil {
 		return err
 	}
-	var tests map[string]tests.StateTest
-	if err := json.Unmarshal(src, &tests); err != nil {
+	var stateTests map[string]tests.StateTest
+	if err := json.Unmarshal(src, &stateTests); err != nil {
+		return err
+	}
+	return runStateTests(stateTests, cfg, opts)
+}
+
+// runStateTestArchive executes the state tests of every JSON file in the given
+// tar archive without extracting it. Tests are named <entry>:<name> after the
+// archive-relative path of the file they are read from.
+func runStateTestArchive(fname string, cfg vm.Config, opts *stateTestOptions) error {
+	f, err := os.Open(fname)
+	if err != nil {
 		return err
 	}
-	// Iterate over all the tests, run them and aggregate the results
-	results := make([]StatetestResult, 0, len(tests))
-	for key, test := range tests {
+	defer f.Close()
+
+	var r io.Reader = f
+	if !strings.HasSuffix(fname, ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %v", fname, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(hdr.Name, ".json") {
+			continue
+		}
+		var stateTests map[string]tests.StateTest
+		if err := json.NewDecoder(tr).Decode(&stateTests); err != nil {
+			err = fmt.Errorf("%s: %v", hdr.Name, err)
+			if !opts.keepGoing {
+				return err
+			}
+			reportStateTestLoadFailure(fname+":"+hdr.Name, err, opts)
+			continue
+		}
+		named := make(map[string]tests.StateTest, len(stateTests))
+		for key, test := range stateTests {
+			named[hdr.Name+":"+key] = test
+		}
+		if err := runStateTests(named, cfg, opts); err != nil {
+			return err
+		}
+	}
+}
+
+// runStateTests executes the given, already decoded state tests.
+func runStateTests(stateTests map[string]tests.StateTest, cfg vm.Config, opts *stateTestOptions) error {
+	if opts.list {
+		listStateTests(os.Stdout, stateTests, opts)
+		return nil
+	}
+	// Iterate over all the tests in order, run them and aggregate the results,
+	// so identical inputs always produce identical output
+	keys := make([]string, 0, len(stateTests))
+	for key := range stateTests {
+		if opts.run != nil && !opts.run.MatchString(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]StatetestResult, 0, len(keys))
+	for _, key := range keys {
+		test := stateTests[key]
+		if opts.prestate != nil {
+			if collisions := test.MergePreState(opts.prestate); len(collisions) > 0 && opts.strict {
+				return fmt.Errorf("test %s: prestate accounts also defined by the test: %v", key, collisions)
+			}
+		}
 		for _, st := range test.Subtests(nil) {
-			if testFork != "" && testFork != st.Fork {
+			if opts.fork != "" && opts.fork != st.Fork {
 				continue
 			}
-			// Run the test and aggregate the result
-			result := &StatetestResult{Name: key, Fork: st.Fork, Pass: true}
-			test.Run(st, cfg, false, rawdb.HashScheme, func(err error, snaps *snapshot.Tree, state *state.StateDB) {
-				if state != nil {
-					root := state.IntermediateRoot(false)
-					result.Root = &root
-					if jsonOut {
-						fmt.Fprintf(os.Stderr, "{\"stateRoot\": \"%#x\"}\n", root)
-					}
+			var result *StatetestResult
+			if _, _, err := tests.GetChainConfig(st.Fork); opts.skipUnknownForks && errors.As(err, new(tests.UnsupportedForkError)) {
+				log.Warn("Skipping subtest of unsupported fork", "name", key, "fork", st.Fork, "index", st.Index)
+				result = &StatetestResult{Name: key, Fork: st.Fork, Index: st.Index, Skipped: true, Error: err.Error()}
+			} else {
+				result = runStateSubtest(key, test, st, cfg, opts)
+				if opts.expected != nil {
+					opts.expected.check(result)
 				}
-				// Dump any state to aid debugging
-				if dump {
-					dump := state.RawDump(nil)
-					result.State = &dump
+				if !result.Pass {
+					opts.failed++
 				}
-				if err != nil {
-					// Test failed, mark as so
-					result.Pass, result.Error = false, err.Error()
+				if opts.abortOnFail && !result.Pass && !result.ExpectedFail {
+					// Trace the failing subtest again if no tracer was requested
+					if cfg.Tracer == nil {
+						traceCfg := cfg
+						traceCfg.Tracer = logger.NewJSONLogger(&logger.Config{EnableMemory: true, EnableReturnData: true}, os.Stderr)
+						runStateSubtest(key, test, st, traceCfg, opts)
+					}
+					// Report the failure alone and in full, whatever the output mode
+					printStateTestResults(os.Stdout, []StatetestResult{*result}, "json")
+					return fmt.Errorf("%w at %s/%s/%d", errStateTestAborted, result.Name, result.Fork, result.Index)
 				}
-			})
+			}
+			if (opts.quiet || opts.stream) && !opts.aggregate() {
+				opts.printResults(os.Stdout, []StatetestResult{*result})
+				continue
+			}
 			results = append(results, *result)
 		}
 	}
-	out, _ := json.MarshalIndent(results, "", "  ")
-	fmt.Println(string(out))
+	switch {
+	case opts.aggregate():
+		opts.results = append(opts.results, results...)
+	case !opts.stream && !opts.quiet:
+		opts.printResults(os.Stdout, results)
+	}
 	return nil
 }
+
+// runStateSubtest executes a single subtest of the given state test. With
+// --max-memory, a subtest exceeding the limit is aborted and reported as failed.
+func runStateSubtest(key string, test tests.StateTest, st tests.StateSubtest, cfg vm.Config, opts *stateTestOptions) (result *StatetestResult) {
+	// Only pay the tracing overhead of counting when explicitly requested
+	var counter *stepCounter
+	if opts.countSteps {
+		counter = newStepCounter(cfg.Tracer)
+		cfg.Tracer = counter
+	}
+	// Sample the heap growth on a goroutine scoped to the subtest
+	var limiter *memoryLimiter
+	if opts.maxMemory > 0 {
+		limiter = newMemoryLimiter(cfg.Tracer, opts.maxMemory)
+		cfg.Tracer = limiter
+		go limiter.watch()
+		defer limiter.stop()
+	}
+	result = &StatetestResult{Name: key, Fork: st.Fork, Index: st.Index, Pass: true, Eips: cfg.ExtraEips}
+	start := time.Now()
+	test.RunWithExecution(st, cfg, false, rawdb.HashScheme, func(err error, snaps *snapshot.Tree, state *state.StateDB, exec tests.StateTestExecution) {
+		result.Elapsed = time.Since(start)
+		result.GasUsed, result.BlobGasUsed = exec.GasUsed, exec.BlobGasUsed
+		// Report the root of every subtest whose state is available,
+		// passing or not, for diffing against other clients
+		if state != nil {
+			root := state.IntermediateRoot(false)
+			result.Root = &root
+			if opts.jsonOut {
+				fmt.Fprintf(os.Stderr, "{\"stateRoot\": \"%#x\"}\n", root)
+			}
+		}
+		if err != nil {
+			// Test failed, mark as so
+			result.Pass, result.Error = false, err.Error()
+		}
+		// Dump any state to aid debugging
+		if state != nil && opts.shouldDump(result) {
+			dump := state.RawDump(nil)
+			if opts.stateDiff {
+				dump = diffStateDump(preStateDump(&test), dump)
+			}
+			result.State = &dump
+			opts.dumped++
+		}
+	})
+	if counter != nil {
+		result.Steps = counter.steps
+	}
+	// The root of a cancelled execution is meaningless, only report the abort
+	if limiter != nil {
+		if err := limiter.err(); err != nil {
+			result.Pass, result.Error, result.Root = false, err.Error(), nil
+			debug.FreeOSMemory()
+		}
+	}
+	return result
+}
+
+// StatetestSubtest identifies a subtest of a state test file.
+type StatetestSubtest struct {
+	Name  string `json:"name"`
+	Fork  string `json:"fork"`
+	Index int    `json:"index"`
+}
+
+// listStateTests writes the subtests of the given state tests to w in the
+// requested format, sorted by name, fork and index, without running them.
+func listStateTests(w io.Writer, stateTests map[string]tests.StateTest, opts *stateTestOptions) {
+	var subtests []StatetestSubtest
+	for key, test := range stateTests {
+		if opts.run != nil && !opts.run.MatchString(key) {
+			continue
+		}
+		for _, st := range test.Subtests(nil) {
+			if opts.fork != "" && opts.fork != st.Fork {
+				continue
+			}
+			subtests = append(subtests, StatetestSubtest{Name: key, Fork: st.Fork, Index: st.Index})
+		}
+	}
+	sort.Slice(subtests, func(i, j int) bool {
+		a, b := subtests[i], subtests[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Fork != b.Fork {
+			return a.Fork < b.Fork
+		}
+		return a.Index < b.Index
+	})
+	if opts.format == "table" {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tFORK\tINDEX")
+		for _, st := range subtests {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", st.Name, st.Fork, st.Index)
+		}
+		tw.Flush()
+		return
+	}
+	out, _ := json.MarshalIndent(subtests, "", "  ")
+	fmt.Fprintln(w, string(out))
+}
+
+// expectedFailures tracks the subtests permitted to fail, identified as
+// name/fork/index, and the results deviating from that expectation.
+type expectedFailures struct {
+	listed         map[string]bool
+	unexpectedFail []string
+	unexpectedPass []string
+}
+
+// loadExpectedFailures reads an expected-failure list with one name/fork/index
+// entry per line. Empty lines and lines starting with '#' are ignored.
+func loadExpectedFailures(path string) (*expectedFailures, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	e := &expectedFailures{listed: make(map[string]bool)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e.listed[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read expected failures: %v", err)
+	}
+	return e, nil
+}
+
+// check marks the result as an expected failure if listed, and records it if
+// it deviates from the expectation.
+func (e *expectedFailures) check(result *StatetestResult) {
+	id := fmt.Sprintf("%s/%s/%d", result.Name, result.Fork, result.Index)
+	result.ExpectedFail = e.listed[id]
+	switch {
+	case result.ExpectedFail && result.Pass:
+		e.unexpectedPass = append(e.unexpectedPass, id)
+	case !result.ExpectedFail && !result.Pass:
+		e.unexpectedFail = append(e.unexpectedFail, id)
+	}
+}
+
+// err returns an error describing the results that deviated from the
+// expected-failure list, if any.
+func (e *expectedFailures) err() error {
+	if len(e.unexpectedFail) == 0 && len(e.unexpectedPass) == 0 {
+		return nil
+	}
+	for _, id := range e.unexpectedFail {
+		log.Error("Unexpected state test failure", "test", id)
+	}
+	for _, id := range e.unexpectedPass {
+		log.Error("Expected state test failure passed", "test", id)
+	}
+	return fmt.Errorf("%d unexpected failures, %d unexpected passes", len(e.unexpectedFail), len(e.unexpectedPass))
+}
+
+// stateTestTableErrorLen is the length to which errors are truncated in the
+// table output format.
+const stateTestTableErrorLen = 80
+
+// printStateTestResults writes the results to w in the requested format.
+func printStateTestResults(w io.Writer, results []StatetestResult, format string) {
+	switch format {
+	case "table":
+		printStateTestTable(w, results)
+	case "csv":
+		printStateTestCSV(w, results)
+	case "diff":
+		printStateTestDiff(w, results)
+	default:
+		out, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Fprintln(w, string(out))
+	}
+}
+
+// printStateTestDiff writes one line per subtest in the form
+//
+//	<name> <fork> <index> <root> <pass>
+//
+// with the columns separated by a single space, the root as 0x-prefixed hex
+// (or "-" if unavailable) and pass as true/false. Lines of all inputs of a run
+// are sorted together by name, fork and index, so the output of different
+// clients can be compared with diff regardless of the order files are run in.
+func printStateTestDiff(w io.Writer, results []StatetestResult) {
+	sorted := make([]StatetestResult, len(results))
+	copy(sorted, results)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Fork != b.Fork {
+			return a.Fork < b.Fork
+		}
+		return a.Index < b.Index
+	})
+	for _, r := range sorted {
+		root := "-"
+		if r.Root != nil {
+			root = r.Root.Hex()
+		}
+		fmt.Fprintf(w, "%s %s %d %s %v\n", r.Name, r.Fork, r.Index, root, r.Pass)
+	}
+}
+
+// printStateTestCSV writes the results as comma-separated values, preceded by
+// a single header line:
+//
+//	name,fork,index,pass,root,gas_used,ns
+//
+// The root is empty if unavailable, gas_used is zero if the message wasn't
+// applied and ns is the wall time of the execution in nanoseconds.
+func printStateTestCSV(w io.Writer, results []StatetestResult) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "fork", "index", "pass", "root", "gas_used", "ns"})
+	for _, r := range results {
+		var root string
+		if r.Root != nil {
+			root = r.Root.Hex()
+		}
+		cw.Write([]string{
+			r.Name,
+			r.Fork,
+			strconv.Itoa(r.Index),
+			strconv.FormatBool(r.Pass),
+			root,
+			strconv.FormatUint(r.GasUsed, 10),
+			strconv.FormatInt(r.Elapsed.Nanoseconds(), 10),
+		})
+	}
+	cw.Flush()
+}
+
+// printStateTestTable writes the results as aligned columns.
+func printStateTestTable(w io.Writer, results []StatetestResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tFORK\tPASS\tERROR")
+	for _, r := range results {
+		errStr := r.Error
+		if len(errStr) > stateTestTableErrorLen {
+			errStr = errStr[:stateTestTableErrorLen-3] + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%s\n", r.Name, r.Fork, r.Pass, errStr)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/internal/cmdtest"
+)
+
+var (
+	statetestSender   = common.HexToAddress("0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	statetestContract = common.HexToAddress("0x1000000000000000000000000000000000000000")
+	statetestUnused   = common.HexToAddress("0x000000000000000000000000000000000000dead")
+)
+
+// decodeStateTestResults decodes the JSON documents of results printed by the
+// statetest command, one per line with --stream or one per input otherwise.
+func decodeStateTestResults(t *testing.T, out []byte) []StatetestResult {
+	var results []StatetestResult
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var doc json.RawMessage
+		if err := dec.Decode(&doc); err == io.EOF {
+			return results
+		} else if err != nil {
+			t.Fatalf("invalid json output: %v\n%s", err, out)
+		}
+		if bytes.HasPrefix(doc, []byte("[")) {
+			var batch []StatetestResult
+			if err := json.Unmarshal(doc, &batch); err != nil {
+				t.Fatalf("invalid results: %v\n%s", err, doc)
+			}
+			results = append(results, batch...)
+		} else {
+			var result StatetestResult
+			if err := json.Unmarshal(doc, &result); err != nil {
+				t.Fatalf("invalid result: %v\n%s", err, doc)
+			}
+			results = append(results, result)
+		}
+	}
+}
+
+// expectAbortedResult checks that the output holds nothing but the failing
+// subtest of fail.json, reported with its state dump.
+func expectAbortedResult(t *testing.T, out []byte) {
+	results := decodeStateTestResults(t, out)
+	if len(results) != 1 {
+		t.Fatalf("have %d results, want 1:\n%s", len(results), out)
+	}
+	if r := results[0]; r.Name != "fail" || r.Pass || r.State == nil {
+		t.Fatalf("want the failing subtest with a state dump, have %+v", r)
+	}
+}
+
+func TestStateTest(t *testing.T) {
+	tt := cmdtest.NewTestCmd(t, nil)
+	for i, tc := range []struct {
+		name        string
+		args        []string
+		files       []string // Fed on stdin, running the command in batch mode
+		expExitCode int
+		check       func(t *testing.T, out []byte)
+	}{
+		{
+			name:        "malformed file",
+			files:       []string{"malformed.json", "fail.json"},
+			expExitCode: 1,
+			check: func(t *testing.T, out []byte) {
+				if len(out) != 0 {
+					t.Fatalf("want no results, have:\n%s", out)
+				}
+			},
+		},
+		{
+			name:        "keep going after a malformed file",
+			args:        []string{"--keep-going"},
+			files:       []string{"malformed.json", "fail.json"},
+			expExitCode: 1,
+			check: func(t *testing.T, out []byte) {
+				results := decodeStateTestResults(t, out)
+				if len(results) != 2 {
+					t.Fatalf("have %d results, want 2:\n%s", len(results), out)
+				}
+				if r := results[0]; !strings.HasSuffix(r.Name, "malformed.json") || r.Pass || r.Error == "" {
+					t.Fatalf("want a failed result for the malformed file, have %+v", r)
+				}
+				if r := results[1]; r.Name != "fail" || r.Fork != "Berlin" || r.Pass || r.Root == nil {
+					t.Fatalf("want the failing subtest with its root, have %+v", r)
+				}
+			},
+		},
+		{
+			name:        "abort on fail",
+			args:        []string{"--abort-on-fail"},
+			files:       []string{"fail.json", "malformed.json"},
+			expExitCode: 1,
+			check:       expectAbortedResult,
+		},
+		{
+			name:        "abort on fail with an aggregate format",
+			args:        []string{"--abort-on-fail", "--format", "table"},
+			files:       []string{"fail.json", "fail.json"},
+			expExitCode: 1,
+			check:       expectAbortedResult,
+		},
+		{
+			name:        "abort on fail with quiet",
+			args:        []string{"--abort-on-fail", "--quiet"},
+			files:       []string{"fail.json"},
+			expExitCode: 1,
+			check:       expectAbortedResult,
+		},
+		{
+			name:        "memory limit",
+			args:        []string{"--max-memory", "1"},
+			files:       []string{"memory.json"},
+			expExitCode: 1,
+			check: func(t *testing.T, out []byte) {
+				results := decodeStateTestResults(t, out)
+				if len(results) != 1 {
+					t.Fatalf("have %d results, want 1:\n%s", len(results), out)
+				}
+				if r := results[0]; r.Pass || r.Root != nil || !strings.HasPrefix(r.Error, "MemoryLimitExceeded") {
+					t.Fatalf("want the subtest aborted over the limit, have %+v", r)
+				}
+			},
+		},
+		{
+			name:        "diff format",
+			args:        []string{"--format", "diff"},
+			files:       []string{"fail.json"},
+			expExitCode: 1,
+			check: func(t *testing.T, out []byte) {
+				if !regexp.MustCompile(`^fail Berlin 0 0x[0-9a-f]{64} false\n$`).Match(out) {
+					t.Fatalf("unexpected diff output:\n%s", out)
+				}
+			},
+		},
+		{
+			name:        "state diff",
+			args:        []string{"--state-diff"},
+			files:       []string{"fail.json"},
+			expExitCode: 1,
+			check: func(t *testing.T, out []byte) {
+				results := decodeStateTestResults(t, out)
+				if len(results) != 1 || results[0].State == nil {
+					t.Fatalf("want a single result with a state dump, have:\n%s", out)
+				}
+				accounts := results[0].State.Accounts
+				if _, ok := accounts[statetestSender]; !ok {
+					t.Errorf("sender missing from the state diff")
+				}
+				if acc, ok := accounts[statetestContract]; !ok || len(acc.Storage) != 1 {
+					t.Errorf("want the contract with its stored slot, have %+v", acc)
+				}
+				if _, ok := accounts[statetestUnused]; ok {
+					t.Errorf("untouched account in the state diff")
+				}
+			},
+		},
+	} {
+		args := append([]string{"statetest"}, tc.args...)
+		tt.Logf("test %d (%s): args: %v", i, tc.name, strings.Join(args, " "))
+		tt.Run("evm-test", args...)
+		for _, file := range tc.files {
+			tt.InputLine("./testdata/statetest/" + file)
+		}
+		tt.CloseStdin()
+
+		tc.check(t, tt.Output())
+		tt.WaitExit()
+		if have, want := tt.ExitStatus(), tc.expExitCode; have != want {
+			t.Fatalf("test %d (%s): wrong exit code, have %d, want %d", i, tc.name, have, want)
+		}
+	}
+}
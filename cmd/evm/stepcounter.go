@@ -0,0 +1,85 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// stepCounter is a lightweight EVM logger counting the executed opcodes of a
+// transaction. All events are forwarded to an optional inner
+// logger, so it can be stacked on top of the tracers selected by other flags.
+type stepCounter struct {
+	inner vm.EVMLogger
+	steps uint64
+}
+
+func newStepCounter(inner vm.EVMLogger) *stepCounter {
+	return &stepCounter{inner: inner}
+}
+
+func (c *stepCounter) CaptureTxStart(gasLimit uint64) {
+	if c.inner != nil {
+		c.inner.CaptureTxStart(gasLimit)
+	}
+}
+
+func (c *stepCounter) CaptureTxEnd(restGas uint64) {
+	if c.inner != nil {
+		c.inner.CaptureTxEnd(restGas)
+	}
+}
+
+func (c *stepCounter) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	if c.inner != nil {
+		c.inner.CaptureStart(env, from, to, create, input, gas, value)
+	}
+}
+
+func (c *stepCounter) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if c.inner != nil {
+		c.inner.CaptureEnd(output, gasUsed, err)
+	}
+}
+
+func (c *stepCounter) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if c.inner != nil {
+		c.inner.CaptureEnter(typ, from, to, input, gas, value)
+	}
+}
+
+func (c *stepCounter) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if c.inner != nil {
+		c.inner.CaptureExit(output, gasUsed, err)
+	}
+}
+
+func (c *stepCounter) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	c.steps++
+	if c.inner != nil {
+		c.inner.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (c *stepCounter) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if c.inner != nil {
+		c.inner.CaptureFault(pc, op, gas, cost, scope, depth, err)
+	}
+}
@@ -44,6 +44,17 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+var (
+	afReplayFromFlag = &cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block number to replay",
+	}
+	afReplayToFlag = &cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block number to replay (default = head)",
+	}
+)
+
 var (
 	initCommand = &cli.Command{
 		Action:    initGenesis,
@@ -138,6 +149,22 @@ be gzipped.`,
 The import-preimages command imports hash preimages from an RLP encoded stream.
 It's deprecated, please use "geth db import" instead.
 `,
+	}
+	afReplayCommand = &cli.Command{
+		Action: afReplay,
+		Name:   "af-replay",
+		Usage:  "Report whether artificial finality would have rejected the stored competing blocks of a range",
+		Flags: flags.Merge([]cli.Flag{
+			afReplayFromFlag,
+			afReplayToFlag,
+			utils.CacheFlag,
+		}, utils.DatabaseFlags),
+		Description: `
+The af-replay command evaluates ECBP1100 (MESS) for every stored non-canonical
+block in the given range, as a reorg from the canonical block of the same
+number, and reports whether the reorg would have been allowed or rejected.
+Blocks lighter than their canonical counterpart would not have been reorged
+to regardless and are reported as such.`,
 	}
 	exportPreimagesCommand = &cli.Command{
 		Action:    exportPreimages,
@@ -384,6 +411,57 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+// afReplay reports what artificial finality would have decided for the stored
+// competing blocks of a range.
+func afReplay(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, true)
+	defer db.Close()
+
+	from, to := ctx.Uint64(afReplayFromFlag.Name), chain.CurrentBlock().Number.Uint64()
+	if ctx.IsSet(afReplayToFlag.Name) && ctx.Uint64(afReplayToFlag.Name) < to {
+		to = ctx.Uint64(afReplayToFlag.Name)
+	}
+	var allowed, rejected, lighter int
+	for number := from; number <= to; number++ {
+		current := chain.GetHeaderByNumber(number)
+		if current == nil {
+			return fmt.Errorf("canonical block %d not found", number)
+		}
+		for _, hash := range rawdb.ReadAllHashes(db, number) {
+			if hash == current.Hash() {
+				continue
+			}
+			proposed := chain.GetHeader(hash, number)
+			if proposed == nil {
+				continue
+			}
+			d, err := chain.MESSDecisionAt(current, proposed)
+			if err != nil {
+				log.Warn("Failed to evaluate competing block", "number", number, "hash", hash, "err", err)
+				continue
+			}
+			verdict := "allowed"
+			switch {
+			case d.ProposedSubchainTD.Cmp(d.LocalSubchainTD) < 0:
+				verdict = "lighter"
+				lighter++
+			case d.Rejected != nil:
+				verdict = "rejected"
+				rejected++
+			default:
+				allowed++
+			}
+			fmt.Printf("number=%d hash=%s canonical=%s common=%d age=%ds verdict=%s\n",
+				number, hash.Hex(), current.Hash().Hex(), d.CommonAncestor.Number.Uint64(), d.Age, verdict)
+		}
+	}
+	fmt.Printf("Replayed blocks %d-%d: allowed=%d rejected=%d lighter=%d\n", from, to, allowed, rejected, lighter)
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if ctx.Args().Len() < 1 {
@@ -181,6 +181,16 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 			cfg.Eth.ECBP1100NoDisable = &enable
 		}
 	}
+	if ctx.IsSet(utils.ECBP1100ControlFileFlag.Name) {
+		cfg.Eth.ECBP1100ControlFile = ctx.Path(utils.ECBP1100ControlFileFlag.Name)
+	}
+	if ctx.IsSet(utils.ECBP1100ConfirmDisableFlag.Name) {
+		cfg.Eth.ECBP1100ConfirmDisable = ctx.Bool(utils.ECBP1100ConfirmDisableFlag.Name)
+	}
+	if ctx.IsSet(utils.ECBP1100RejectionLogIntervalFlag.Name) {
+		interval := ctx.Duration(utils.ECBP1100RejectionLogIntervalFlag.Name)
+		cfg.Eth.ECBP1100RejectionLogInterval = &interval
+	}
 	if ctx.IsSet(utils.OverrideECBP1100DeactivateFlag.Name) {
 		if n := ctx.Uint64(utils.OverrideECBP1100DeactivateFlag.Name); n != math.MaxUint64 {
 			cfg.Eth.OverrideECBP1100Deactivate = &n
@@ -169,6 +169,9 @@ var (
 		utils.MinerNotifyFullFlag,
 		utils.ECBP1100Flag,
 		utils.ECBP1100NoDisableFlag,
+		utils.ECBP1100ControlFileFlag,
+		utils.ECBP1100ConfirmDisableFlag,
+		utils.ECBP1100RejectionLogIntervalFlag,
 		utils.OverrideECBP1100DeactivateFlag,
 		configFileFlag,
 	}, utils.NetworkFlags, utils.DatabaseFlags)
@@ -239,6 +242,7 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		afReplayCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -1097,6 +1097,22 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 		Usage:    "Short-circuit ECBP-1100 (MESS) disable mechanisms; (yields a permanent-once-activated state, deactivating auto-shutoff mechanisms)",
 		Category: flags.DeprecatedCategory,
 	}
+	ECBP1100ControlFileFlag = &cli.PathFlag{
+		Name:     "ecbp1100.controlfile",
+		Usage:    "File polled for 'enable' or 'disable' commands toggling ECBP-1100 (MESS), as a fallback to the RPC",
+		Category: flags.EthCategory,
+	}
+	ECBP1100ConfirmDisableFlag = &cli.BoolFlag{
+		Name:     "ecbp1100.confirmdisable",
+		Usage:    "Require disabling ECBP-1100 (MESS) via admin_setArtificialFinality to be confirmed with a challenge token",
+		Category: flags.EthCategory,
+	}
+	ECBP1100RejectionLogIntervalFlag = &cli.DurationFlag{
+		Name:     "ecbp1100.rejectionloginterval",
+		Usage:    "Minimum interval between repeated warnings about ECBP-1100 (MESS) rejections of reorgs with the same common ancestor (0 = log all)",
+		Value:    core.DefaultAFRejectionLogInterval,
+		Category: flags.EthCategory,
+	}
 
 	MetricsEnableInfluxDBV2Flag = &cli.BoolFlag{
 		Name:     "metrics.influxdbv2",
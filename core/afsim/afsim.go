@@ -0,0 +1,162 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package afsim simulates artificial finality (ECBP1100, MESS) decisions on
+// synthetic header chains, for validating MESS against modeled attacks.
+package afsim
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params/types/coregeth"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+)
+
+// Chain is a synthetic header chain with controllable timestamps and
+// difficulties. It implements consensus.ChainHeaderReader, so the decisions
+// are made by the real ForkChoice and MESS code rather than a reimplementation.
+type Chain struct {
+	config  ctypes.ChainConfigurator
+	genesis *types.Header
+	current *types.Header
+	headers map[common.Hash]*types.Header
+	tds     map[common.Hash]*big.Int
+}
+
+// NewChain creates a synthetic chain with MESS active from genesis.
+func NewChain(difficulty int64) *Chain {
+	config := &coregeth.CoreGethChainConfig{}
+	transition := uint64(0)
+	config.SetECBP1100Transition(&transition)
+
+	genesis := &types.Header{Number: new(big.Int), Difficulty: big.NewInt(difficulty)}
+	return &Chain{
+		config:  config,
+		genesis: genesis,
+		current: genesis,
+		headers: map[common.Hash]*types.Header{genesis.Hash(): genesis},
+		tds:     map[common.Hash]*big.Int{genesis.Hash(): big.NewInt(difficulty)},
+	}
+}
+
+// Genesis returns the genesis header of the chain.
+func (c *Chain) Genesis() *types.Header { return c.genesis }
+
+// SetHead sets the current head of the chain, without any fork choice.
+func (c *Chain) SetHead(header *types.Header) { c.current = header }
+
+// Extend appends n headers to parent, spaced interval seconds apart and each
+// with the given difficulty. The miner distinguishes competing segments.
+func (c *Chain) Extend(parent *types.Header, n int, interval uint64, difficulty int64, miner byte) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Coinbase:   common.Address{miner},
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       parent.Time + interval,
+			Difficulty: big.NewInt(difficulty),
+		}
+		c.headers[header.Hash()] = header
+		c.tds[header.Hash()] = new(big.Int).Add(c.tds[parent.Hash()], header.Difficulty)
+		headers[i], parent = header, header
+	}
+	return headers
+}
+
+// Propose runs the fork choice for a reorg to proposed, updating the current
+// head if accepted.
+func (c *Chain) Propose(proposed *types.Header) (bool, error) {
+	reorg, err := core.NewForkChoice(c, nil).ReorgNeeded(c.current, proposed)
+	if reorg {
+		c.current = proposed
+	}
+	return reorg, err
+}
+
+// Config implements consensus.ChainHeaderReader.
+func (c *Chain) Config() ctypes.ChainConfigurator { return c.config }
+
+// CurrentHeader implements consensus.ChainHeaderReader.
+func (c *Chain) CurrentHeader() *types.Header { return c.current }
+
+// GetHeader implements consensus.ChainHeaderReader.
+func (c *Chain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+// GetHeaderByHash implements consensus.ChainHeaderReader.
+func (c *Chain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+
+// GetHeaderByNumber implements consensus.ChainHeaderReader.
+func (c *Chain) GetHeaderByNumber(number uint64) *types.Header {
+	for header := c.current; header != nil; header = c.headers[header.ParentHash] {
+		if header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+// GetTd implements consensus.ChainHeaderReader.
+func (c *Chain) GetTd(hash common.Hash, number uint64) *big.Int {
+	if c.GetHeader(hash, number) == nil {
+		return nil
+	}
+	return c.tds[hash]
+}
+
+// Attack describes an attacker forking off the honest chain and publishing a
+// competing segment.
+type Attack struct {
+	HonestBlocks   int     // blocks mined by the honest network after the fork
+	AttackerBlocks int     // blocks mined privately by the attacker after the fork
+	Hashrate       float64 // attacker hashrate relative to the honest network
+}
+
+// SimulateAttack mines an honest chain and a competing attacker segment from
+// a common ancestor, both at a 13 second block time, and reports whether MESS
+// lets the attacker segment reorg the honest chain when published.
+func SimulateAttack(attack Attack) (bool, error) {
+	const (
+		interval   = 13
+		difficulty = 1_000_000
+	)
+	if attack.HonestBlocks <= 0 || attack.AttackerBlocks <= 0 {
+		return false, fmt.Errorf("invalid attack: %d honest and %d attacker blocks", attack.HonestBlocks, attack.AttackerBlocks)
+	}
+	chain := NewChain(difficulty)
+
+	ancestor := chain.Extend(chain.Genesis(), 100, interval, difficulty, 0x01)
+	fork := ancestor[len(ancestor)-1]
+	chain.SetHead(fork)
+
+	honest := chain.Extend(fork, attack.HonestBlocks, interval, difficulty, 0x01)
+	chain.SetHead(honest[len(honest)-1])
+
+	// More hashrate at the same block time shows up as higher difficulty.
+	attacker := chain.Extend(fork, attack.AttackerBlocks, interval, int64(difficulty*attack.Hashrate), 0x02)
+	return chain.Propose(attacker[len(attacker)-1])
+}
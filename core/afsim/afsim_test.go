@@ -0,0 +1,49 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package afsim
+
+import "testing"
+
+func TestSimulatedAttacks(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		attack Attack
+		reorg  bool
+	}{
+		// Natural short reorgs with a heavier competing segment go through.
+		{"short reorg", Attack{HonestBlocks: 2, AttackerBlocks: 2, Hashrate: 1.1}, true},
+		// Selfish mining: the attacker keeps a lead of one block and publishes
+		// as soon as the honest network catches up.
+		{"selfish mining", Attack{HonestBlocks: 1, AttackerBlocks: 2, Hashrate: 1.0}, true},
+		// Private mining for an hour with a slight hashrate majority must not
+		// be able to double spend once published.
+		{"private mine and publish", Attack{HonestBlocks: 277, AttackerBlocks: 277, Hashrate: 1.1}, false},
+		{"private mine and publish, 2x hashrate", Attack{HonestBlocks: 277, AttackerBlocks: 277, Hashrate: 2}, false},
+		// An overwhelming majority still wins, as the curve is bounded.
+		{"private mine and publish, 3x hashrate", Attack{HonestBlocks: 277, AttackerBlocks: 277, Hashrate: 3}, true},
+		// A day long private chain needs more than 31x the hashrate.
+		{"long range", Attack{HonestBlocks: 6646, AttackerBlocks: 6646, Hashrate: 30}, false},
+	} {
+		reorg, err := SimulateAttack(c.attack)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if reorg != c.reorg {
+			t.Errorf("%s: reorg=%v, want %v", c.name, reorg, c.reorg)
+		}
+	}
+}
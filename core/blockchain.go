@@ -261,8 +261,30 @@ type BlockChain struct {
 	forker     *ForkChoice
 	vmConfig   vm.Config
 
-	artificialFinalityNoDisable     *int32 // manual override prevents disabling artificial finality feature activation
-	artificialFinalityEnabledStatus int32  // toggles artificial finality features; will be always 1 if artificialFinalityForce=1
+	artificialFinalityMu            sync.Mutex // serializes toggling of the artificial finality settings below
+	artificialFinalityNoDisable     *int32     // manual override prevents disabling artificial finality feature activation
+	artificialFinalityEnabledStatus int32      // toggles artificial finality features; will be always 1 if artificialFinalityForce=1
+
+	reorgWhitelist   map[common.Hash]struct{} // reorg targets exempted from artificial finality by the operator
+	reorgWhitelistMu sync.RWMutex
+
+	consensusScorers   []ConsensusScorer // artificial finality mechanisms evaluated in order for reorgs
+	consensusScorersMu sync.RWMutex
+
+	afRejectionMode atomic.Int32                     // AFRejectionMode applied to reorgs disallowed by artificial finality
+	afQuarantine    *afQuarantineSet                 // recently rejected proposed heads, in quarantine mode
+	afRejections    *afRejectionLog                  // recent reorgs disallowed by artificial finality
+	afLastAccepted  atomic.Pointer[ReorgDecision]    // most recent reorg allowed by artificial finality
+	afObserver      atomic.Pointer[func(AFDecision)] // called with every artificial finality decision
+	afDecisions     chan AFDecision                  // decisions queued for afObserver
+
+	afConfirmDisable   atomic.Bool        // whether disabling artificial finality by the operator needs confirmation
+	afDisableChallenge afDisableChallenge // pending operator request to disable artificial finality
+
+	afLogger   log.Logger   // logger for artificial finality decisions
+	afLogLevel atomic.Int32 // verbosity of afLogger, or -1 to follow the global verbosity
+
+	afLogLimiter *afLogLimiter // rate limit of artificial finality warnings
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -282,6 +304,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *genesis
 	if _, ok := genesisErr.(*confp.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
+	if err := ValidateArtificialFinalityConfig(chainConfig); err != nil {
+		return nil, err
+	}
 	log.Info("")
 	log.Info(strings.Repeat("-", 153))
 	// TODO meowsbits implement prettier Strings (aka 'Description()') for chain configurator implementations.
@@ -324,6 +349,15 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *genesis
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
 	}
+	bc.afLogLevel.Store(-1)
+	bc.afLogger = log.New()
+	bc.afLogger.SetHandler(bc.afLogHandler())
+	bc.loadReorgWhitelist()
+	bc.consensusScorers = []ConsensusScorer{&messScorer{bc: bc}}
+	bc.afQuarantine = newAFQuarantineSet(afQuarantineLimit, afQuarantineCooldown)
+	bc.afLogLimiter = newAFLogLimiter(DefaultAFRejectionLogInterval)
+	bc.afRejections = newAFRejectionLog(afRejectionWindow)
+	bc.afDecisions = make(chan AFDecision, afObserverQueue)
 
 	bc.currentBlock.Store(nil)
 	bc.currentSnapBlock.Store(nil)
@@ -343,6 +377,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *genesis
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	bc.warnArtificialFinalityInactive()
 	// Make sure the state associated with the block is available, or log out
 	// if there is no available state, waiting for state sync.
 	head := bc.CurrentBlock()
@@ -459,6 +494,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *genesis
 	bc.wg.Add(1)
 	go bc.updateFutureBlocks()
 
+	// Start the artificial finality observer notifier.
+	bc.wg.Add(1)
+	go bc.afObserverLoop()
+
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*confp.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -1,26 +1,156 @@
 package core
 
 import (
+	crand "crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
 )
 
 // errReorgFinality represents an error caused by artificial finality mechanisms.
 var errReorgFinality = errors.New("finality-enforced invalid new chain")
 
+// errReorgFinalityQuarantined represents a reorg dropped because the proposed
+// chain extends a recently rejected one, in quarantine mode.
+var errReorgFinalityQuarantined = fmt.Errorf("%w (quarantined)", errReorgFinality)
+
+// errReorgFinalityMESS represents a reorg disallowed by ECBP1100 (MESS). It
+// wraps errReorgFinality, so either can be matched with errors.Is.
+var errReorgFinalityMESS = fmt.Errorf("%w (mess)", errReorgFinality)
+
+// errNoCommonAncestor is returned for reorgs whose chains don't meet within the
+// available headers, for example after the fork point was pruned. Such a reorg
+// can't be scored, so it is conservatively disallowed: the error wraps
+// errReorgFinality.
+var errNoCommonAncestor = fmt.Errorf("%w (no common ancestor)", errReorgFinality)
+
+func newErrNoCommonAncestor(current, proposed *types.Header) error {
+	return fmt.Errorf("%w: current.bno=%d current.hash=%s proposed.bno=%d proposed.hash=%s", errNoCommonAncestor,
+		current.Number.Uint64(), current.Hash().Hex(), proposed.Number.Uint64(), proposed.Hash().Hex())
+}
+
+// DefaultMESSTipGrace is the number of blocks below the current head within
+// which the common ancestor of a reorg exempts it from ECBP1100 (MESS), unless
+// the chain config sets its own. Such reorgs are normal churn at the tip while
+// blocks propagate.
+const DefaultMESSTipGrace = 2
+
+// messTipGrace returns the MESS tip grace of the given chain config.
+func messTipGrace(config ctypes.ChainConfigurator) uint64 {
+	if grace := config.GetMESSTipGrace(); grace != nil {
+		return *grace
+	}
+	return DefaultMESSTipGrace
+}
+
+// DefaultMESSMarginWarnThreshold is the default MESS margin below which an
+// accepted reorg is reported as approaching the rejection threshold, unless the
+// chain config sets its own.
+const DefaultMESSMarginWarnThreshold = 1.2
+
+// messMarginWarnThreshold returns the MESS margin warn threshold of the given
+// chain config.
+func messMarginWarnThreshold(config ctypes.ChainConfigurator) float64 {
+	if margin := config.GetAFWarnMargin(); margin != nil {
+		return *margin
+	}
+	return DefaultMESSMarginWarnThreshold
+}
+
+var messMarginWarnMeter = metrics.NewRegisteredMeter("chain/af/mess/marginwarn", nil)
+
+// afRejectionMeter counts every reorg disallowed by artificial finality, whether
+// or not its log lines are suppressed by the rejection log interval.
+var afRejectionMeter = metrics.NewRegisteredMeter("chain/af/rejected", nil)
+
+var afEvalTimer = metrics.NewRegisteredTimer("chain/af/eval", nil)
+
+// afObserverDroppedMeter counts the decisions dropped as the observer fell behind.
+var afObserverDroppedMeter = metrics.NewRegisteredMeter("chain/af/observer/dropped", nil)
+
+// afEvalDepthBuckets split the artificial finality evaluation time by the
+// depth of the current segment, from the common ancestor to the current head.
+var afEvalDepthBuckets = []struct {
+	name     string
+	maxDepth uint64
+	timer    metrics.Timer
+}{
+	{"depth16", 16, metrics.NewRegisteredTimer("chain/af/eval/depth16", nil)},
+	{"depth256", 256, metrics.NewRegisteredTimer("chain/af/eval/depth256", nil)},
+	{"depth4096", 4096, metrics.NewRegisteredTimer("chain/af/eval/depth4096", nil)},
+	{"deep", math.MaxUint64, metrics.NewRegisteredTimer("chain/af/eval/deep", nil)},
+}
+
+// afEvalDepthBucket returns the index of the afEvalDepthBuckets entry for the
+// given segment depth.
+func afEvalDepthBucket(depth uint64) int {
+	for i, b := range afEvalDepthBuckets {
+		if depth <= b.maxDepth {
+			return i
+		}
+	}
+	return len(afEvalDepthBuckets) - 1
+}
+
+// ConsensusScorer is an artificial finality mechanism scoring a proposed reorg.
+type ConsensusScorer interface {
+	// ScoreReorg returns a non-nil error if the reorg from current to proposed,
+	// forking at commonAncestor, should be disallowed.
+	ScoreReorg(commonAncestor, current, proposed *types.Header) error
+}
+
+// messScorer implements ConsensusScorer with ECBP1100 (MESS).
+type messScorer struct {
+	bc *BlockChain
+}
+
+func (s *messScorer) ScoreReorg(commonAncestor, current, proposed *types.Header) error {
+	var cmp SegmentComparison
+	if err := ecbp1100WithCurve(s.bc.afLimitedLogger(commonAncestor), s.bc.chainConfig, messCurvePolynomialV, commonAncestor, current, proposed, s.bc.GetTd, s.bc.GetHeader, &cmp); err != nil {
+		return err
+	}
+	// The reorg is allowed, but flag it if it came close to being rejected.
+	// Reorgs exempt at the tip are allowed without being compared.
+	if cmp.LocalSubchainTD != nil && cmp.Margin < messMarginWarnThreshold(s.bc.chainConfig) {
+		messMarginWarnMeter.Mark(1)
+	}
+	return nil
+}
+
+// AddConsensusScorer registers an additional artificial finality mechanism.
+// Scorers are evaluated in registration order after the built-in MESS scorer.
+// Any rejection disallows the reorg, and the returned error reports the
+// rejections of all scorers in that order.
+func (bc *BlockChain) AddConsensusScorer(scorer ConsensusScorer) {
+	bc.consensusScorersMu.Lock()
+	defer bc.consensusScorersMu.Unlock()
+
+	bc.consensusScorers = append(bc.consensusScorers, scorer)
+}
+
 // ArtificialFinalityNoDisable overrides toggling of AF features, forcing it on.
 // n  = 1 : ON
 // n != 1 : OFF
 func (bc *BlockChain) ArtificialFinalityNoDisable(n int32) {
-	log.Warn("Deactivating ECBP1100 (MESS) safety mechanisms", "always on", true)
+	bc.artificialFinalityMu.Lock()
+	defer bc.artificialFinalityMu.Unlock()
+
+	bc.afLogger.Warn("Deactivating ECBP1100 (MESS) safety mechanisms", "always on", true)
 	bc.artificialFinalityNoDisable = new(int32)
 	atomic.StoreInt32(bc.artificialFinalityNoDisable, n)
 
@@ -38,7 +168,7 @@ func (bc *BlockChain) ArtificialFinalityNoDisable(n int32) {
 				logActivationBlock = *logActivationBlockRaw
 			}
 
-			log.Warn(`Deactivate-ECBP1100 (MESS) block activation number is set together with '--ecbp1100.nodisable'.
+			bc.afLogger.Warn(`Deactivate-ECBP1100 (MESS) block activation number is set together with '--ecbp1100.nodisable'.
 The --ecbp1100.nodisable feature prevents the toggling of ECBP1100 (MESS) artificial finality with its safety mechanisms of low peer count and stale head.
 ECBP1100 (MESS) is scheduled for network-wide deactivation, rendering the --ecbp1100.nodisable feature anachronistic.
 `, "ECBP1100 activation block", logActivationBlock,
@@ -47,6 +177,20 @@ ECBP1100 (MESS) is scheduled for network-wide deactivation, rendering the --ecbp
 	}
 }
 
+// ClearArtificialFinalityNoDisable removes any override set by
+// ArtificialFinalityNoDisable, so artificial finality can be toggled normally
+// again. The current enabled status is left as is.
+func (bc *BlockChain) ClearArtificialFinalityNoDisable() {
+	bc.artificialFinalityMu.Lock()
+	defer bc.artificialFinalityMu.Unlock()
+
+	if bc.artificialFinalityNoDisable == nil {
+		return
+	}
+	bc.afLogger.Warn("Cleared ECBP1100 (MESS) no-disable override", "previous", atomic.LoadInt32(bc.artificialFinalityNoDisable), "enabled", bc.IsArtificialFinalityEnabled())
+	bc.artificialFinalityNoDisable = nil
+}
+
 // EnableArtificialFinality enables and disable artificial finality features for the blockchain.
 // Currently toggled features include:
 // - ECBP1100-MESS: modified exponential subject scoring
@@ -54,12 +198,24 @@ ECBP1100 (MESS) is scheduled for network-wide deactivation, rendering the --ecbp
 // This level of activation works BELOW the chain configuration for any of the
 // potential features. eg. If ECBP1100 is not activated at the chain config x block number,
 // then calling bc.EnableArtificialFinality(true) will be a noop.
-// The method is idempotent.
+// The method is idempotent and safe for concurrent use; the no-disable check and
+// the status update are performed as a single step under artificialFinalityMu,
+// so a concurrent enable cannot be overwritten by a disable that was about to
+// be refused.
 func (bc *BlockChain) EnableArtificialFinality(enable bool, logValues ...interface{}) {
+	bc.artificialFinalityMu.Lock()
+	defer bc.artificialFinalityMu.Unlock()
+
+	bc.enableArtificialFinality(enable, logValues...)
+}
+
+// enableArtificialFinality implements EnableArtificialFinality, assuming
+// artificialFinalityMu is held.
+func (bc *BlockChain) enableArtificialFinality(enable bool, logValues ...interface{}) {
 	// Short circuit if AF state is enabled and nodisable=true.
 	if bc.artificialFinalityNoDisable != nil && atomic.LoadInt32(bc.artificialFinalityNoDisable) == 1 &&
 		bc.IsArtificialFinalityEnabled() && !enable {
-		log.Warn("Preventing disable artificial finality", "enabled", true, "nodisable", true)
+		bc.afLogger.Warn("Preventing disable artificial finality", "enabled", true, "nodisable", true)
 		return
 	}
 
@@ -72,17 +228,188 @@ func (bc *BlockChain) EnableArtificialFinality(enable bool, logValues ...interfa
 		statusLog = "Disabled"
 		atomic.StoreInt32(&bc.artificialFinalityEnabledStatus, 0)
 	}
-	if !bc.chainConfig.IsEnabled(bc.chainConfig.GetECBP1100Transition, bc.CurrentHeader().Number) {
-		// Don't log anything if the config hasn't enabled it yet.
+	// The head may not be set yet very early during startup, in which case the
+	// config activation (and so whether to log) can't be determined.
+	head := bc.CurrentHeader()
+	if head == nil || !bc.chainConfig.IsEnabled(bc.chainConfig.GetECBP1100Transition, head.Number) {
+		// Don't log the status if the config hasn't enabled it yet, but point out
+		// that enabling it is a noop.
+		if enable && head != nil {
+			bc.warnArtificialFinalityInactive()
+		}
 		return
 	}
-	logFn := log.Warn // Deactivated and enabled
+	logFn := bc.afLogger.Warn // Deactivated and enabled
 	if enable {
-		logFn = log.Info // Activated and enabled
+		logFn = bc.afLogger.Info // Activated and enabled
 	}
 	logFn(fmt.Sprintf("%s artificial finality features", statusLog), logValues...)
 }
 
+// ValidateArtificialFinalityConfig sanity-checks the artificial finality
+// settings of the chain config, so that a misconfiguration is reported at
+// startup rather than at the first reorg evaluated.
+func ValidateArtificialFinalityConfig(config ctypes.ChainConfigurator) error {
+	transition, deactivate := config.GetECBP1100Transition(), config.GetECBP1100DeactivateTransition()
+	if deactivate != nil {
+		if transition == nil {
+			return fmt.Errorf("invalid artificial finality config: ECBP1100 deactivation block %d set without an activation block", *deactivate)
+		}
+		if *deactivate <= *transition {
+			return fmt.Errorf("invalid artificial finality config: ECBP1100 deactivation block %d not after activation block %d", *deactivate, *transition)
+		}
+	}
+	if tb := config.GetECBP1100TieBreak(); tb != nil {
+		switch *tb {
+		case ctypes.ECBP1100TieBreak_FavorProposed, ctypes.ECBP1100TieBreak_FavorIncumbent:
+		default:
+			return fmt.Errorf("invalid artificial finality config: unknown ECBP1100 tie break %v", *tb)
+		}
+	}
+	if m := config.GetAFWarnMargin(); m != nil && !(*m > 0 && !math.IsInf(*m, 0)) {
+		return fmt.Errorf("invalid artificial finality config: warn margin %v is not a positive number", *m)
+	}
+	return nil
+}
+
+// ArtificialFinalityInactiveReason explains why artificial finality is enabled
+// but has no effect because ECBP1100 is not activated by the chain config at the
+// current head. It returns an empty string if artificial finality is disabled
+// or active.
+func (bc *BlockChain) ArtificialFinalityInactiveReason() string {
+	head := bc.CurrentHeader()
+	if !bc.IsArtificialFinalityEnabled() || head == nil || bc.chainConfig.IsEnabled(bc.chainConfig.GetECBP1100Transition, head.Number) {
+		return ""
+	}
+	transition := bc.chainConfig.GetECBP1100Transition()
+	if transition == nil {
+		return "ECBP1100 transition not configured"
+	}
+	if deactivate := bc.chainConfig.GetECBP1100DeactivateTransition(); deactivate != nil && head.Number.Uint64() >= *deactivate {
+		return fmt.Sprintf("ECBP1100 deactivated at block %d, head at block %d", *deactivate, head.Number.Uint64())
+	}
+	return fmt.Sprintf("ECBP1100 transition at block %d not reached, head at block %d", *transition, head.Number.Uint64())
+}
+
+// warnArtificialFinalityInactive logs a warning if artificial finality is
+// enabled but inactive on a chain scheduling ECBP1100. Chains without an
+// ECBP1100 transition are not expected to use it, so are not warned about.
+func (bc *BlockChain) warnArtificialFinalityInactive() {
+	if bc.chainConfig.GetECBP1100Transition() == nil {
+		return
+	}
+	if reason := bc.ArtificialFinalityInactiveReason(); reason != "" {
+		bc.afLogger.Warn("Artificial finality enabled but inactive", "reason", reason)
+	}
+}
+
+// SetArtificialFinality enables or disables artificial finality features on
+// behalf of the operator, like EnableArtificialFinality, and persists the choice
+// so that it is restored on restart. The automatic toggles of the sync loop
+// still apply afterwards.
+func (bc *BlockChain) SetArtificialFinality(enable bool, logValues ...interface{}) {
+	bc.artificialFinalityMu.Lock()
+	defer bc.artificialFinalityMu.Unlock()
+
+	bc.enableArtificialFinality(enable, logValues...)
+	rawdb.WriteArtificialFinalityEnabled(bc.db, enable)
+}
+
+// afDisableChallengeTTL is how long a challenge issued by
+// SetArtificialFinalityConfirmed remains valid.
+const afDisableChallengeTTL = 10 * time.Second
+
+// errAFDisableChallenge is returned when disabling artificial finality is
+// confirmed with an unknown or expired challenge token.
+var errAFDisableChallenge = errors.New("invalid or expired artificial finality disable challenge")
+
+// afDisableChallenge is a pending request to disable artificial finality.
+type afDisableChallenge struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// SetArtificialFinalityDisableConfirmation sets whether disabling artificial
+// finality through SetArtificialFinalityConfirmed requires a second call
+// confirming a challenge token.
+func (bc *BlockChain) SetArtificialFinalityDisableConfirmation(require bool) {
+	bc.afConfirmDisable.Store(require)
+}
+
+// SetArtificialFinalityConfirmed is SetArtificialFinality for operator
+// interfaces such as the RPC. If disable confirmation is required, a disable
+// request without a token only returns a challenge token, and the disable takes
+// effect once requested again with that token within afDisableChallengeTTL.
+func (bc *BlockChain) SetArtificialFinalityConfirmed(enable bool, token string) (challenge string, err error) {
+	if enable || !bc.afConfirmDisable.Load() {
+		bc.SetArtificialFinality(enable, "reason", "operator")
+		return "", nil
+	}
+	c := &bc.afDisableChallenge
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if token == "" {
+		var b [8]byte
+		if _, err := crand.Read(b[:]); err != nil {
+			return "", err
+		}
+		c.token, c.expires = hexutil.Encode(b[:]), time.Now().Add(afDisableChallengeTTL)
+		bc.afLogger.Warn("Artificial finality disable requested, awaiting confirmation", "challenge", c.token, "ttl", afDisableChallengeTTL)
+		return c.token, nil
+	}
+	if c.token == "" || token != c.token || time.Now().After(c.expires) {
+		bc.afLogger.Warn("Rejected artificial finality disable confirmation", "challenge", token)
+		return "", errAFDisableChallenge
+	}
+	c.token = ""
+	bc.afLogger.Warn("Artificial finality disable confirmed", "challenge", token)
+	bc.SetArtificialFinality(false, "reason", "operator")
+	return "", nil
+}
+
+// RestoreArtificialFinality restores the artificial finality status last chosen
+// by the operator with SetArtificialFinality, if any. It is meant to be called
+// once at startup, and skipped if the node config sets the status explicitly.
+func (bc *BlockChain) RestoreArtificialFinality() {
+	enabled := rawdb.ReadArtificialFinalityEnabled(bc.db)
+	if enabled == nil {
+		return
+	}
+	bc.artificialFinalityMu.Lock()
+	defer bc.artificialFinalityMu.Unlock()
+
+	bc.afLogger.Info("Restored operator artificial finality status", "enabled", *enabled)
+	bc.enableArtificialFinality(*enabled, "reason", "restored")
+}
+
+// SetArtificialFinalityLogLevel sets the verbosity of artificial finality
+// decision logs independently of the global verbosity, which they then bypass.
+// A negative level restores the global verbosity for them.
+func (bc *BlockChain) SetArtificialFinalityLogLevel(lvl log.Lvl) {
+	bc.afLogLevel.Store(int32(lvl))
+}
+
+// afLogHandler returns the handler of afLogger, filtering records by the
+// artificial finality log level if set, or by the root handler otherwise.
+func (bc *BlockChain) afLogHandler() log.Handler {
+	return log.FuncHandler(func(r *log.Record) error {
+		lvl := bc.afLogLevel.Load()
+		if lvl < 0 {
+			return log.Root().GetHandler().Log(r)
+		}
+		if r.Lvl > log.Lvl(lvl) {
+			return nil
+		}
+		h := log.Root().GetHandler()
+		if glog, ok := h.(*log.GlogHandler); ok {
+			h = glog.Handler()
+		}
+		return h.Log(r)
+	})
+}
+
 // IsArtificialFinalityEnabled returns the status of the blockchain's artificial
 // finality feature setting.
 // This status is agnostic of feature activation by chain configuration.
@@ -90,6 +417,861 @@ func (bc *BlockChain) IsArtificialFinalityEnabled() bool {
 	return atomic.LoadInt32(&bc.artificialFinalityEnabledStatus) == 1
 }
 
+// WhitelistReorgTarget exempts any proposed chain segment containing the block
+// with the given hash from artificial finality checks. It is an escape hatch for
+// manual recovery during incidents, and is persisted so that it survives a restart.
+func (bc *BlockChain) WhitelistReorgTarget(hash common.Hash) {
+	bc.reorgWhitelistMu.Lock()
+	defer bc.reorgWhitelistMu.Unlock()
+
+	bc.reorgWhitelist[hash] = struct{}{}
+	rawdb.WriteReorgWhitelist(bc.db, bc.reorgWhitelistHashes())
+	bc.afLogger.Warn("Whitelisted reorg target, artificial finality will be bypassed", "hash", hash, "whitelisted", len(bc.reorgWhitelist))
+}
+
+// ClearReorgWhitelist removes all manually whitelisted reorg targets.
+func (bc *BlockChain) ClearReorgWhitelist() {
+	bc.reorgWhitelistMu.Lock()
+	defer bc.reorgWhitelistMu.Unlock()
+
+	bc.reorgWhitelist = make(map[common.Hash]struct{})
+	rawdb.DeleteReorgWhitelist(bc.db)
+	bc.afLogger.Warn("Cleared reorg whitelist")
+}
+
+// ReorgWhitelist returns the manually whitelisted reorg targets.
+func (bc *BlockChain) ReorgWhitelist() []common.Hash {
+	bc.reorgWhitelistMu.RLock()
+	defer bc.reorgWhitelistMu.RUnlock()
+
+	return bc.reorgWhitelistHashes()
+}
+
+// reorgWhitelistHashes returns the whitelist as a slice, assuming the lock is held.
+func (bc *BlockChain) reorgWhitelistHashes() []common.Hash {
+	hashes := make([]common.Hash, 0, len(bc.reorgWhitelist))
+	for hash := range bc.reorgWhitelist {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// loadReorgWhitelist restores the persisted reorg whitelist from the database.
+func (bc *BlockChain) loadReorgWhitelist() {
+	bc.reorgWhitelistMu.Lock()
+	defer bc.reorgWhitelistMu.Unlock()
+
+	bc.reorgWhitelist = make(map[common.Hash]struct{})
+	for _, hash := range rawdb.ReadReorgWhitelist(bc.db) {
+		bc.reorgWhitelist[hash] = struct{}{}
+	}
+	if len(bc.reorgWhitelist) > 0 {
+		bc.afLogger.Warn("Loaded reorg whitelist, artificial finality will be bypassed for whitelisted targets", "whitelisted", len(bc.reorgWhitelist))
+	}
+}
+
+// whitelistedReorgTarget returns the whitelisted block contained in the proposed
+// segment (proposed back to, but excluding, the common ancestor), if any.
+func (bc *BlockChain) whitelistedReorgTarget(commonAncestor, proposed *types.Header) (common.Hash, bool) {
+	bc.reorgWhitelistMu.RLock()
+	defer bc.reorgWhitelistMu.RUnlock()
+
+	if len(bc.reorgWhitelist) == 0 {
+		return common.Hash{}, false
+	}
+	for h := proposed; h != nil && h.Number.Cmp(commonAncestor.Number) > 0; h = bc.GetHeader(h.ParentHash, h.Number.Uint64()-1) {
+		if _, ok := bc.reorgWhitelist[h.Hash()]; ok {
+			return h.Hash(), true
+		}
+	}
+	return common.Hash{}, false
+}
+
+// evaluateArtificialFinality runs the artificial finality checks for a reorg
+// from current to proposed, returning a non-nil error if it should be disallowed.
+func (bc *BlockChain) evaluateArtificialFinality(commonAncestor, current, proposed *types.Header) error {
+	if commonAncestor == nil {
+		afRejectionMeter.Mark(1)
+		return newErrNoCommonAncestor(current, proposed)
+	}
+	start := time.Now()
+	mechanism, err := bc.scoreArtificialFinality(commonAncestor, current, proposed)
+	afEvalTimer.UpdateSince(start)
+	if current.Number.Cmp(commonAncestor.Number) >= 0 {
+		depth := current.Number.Uint64() - commonAncestor.Number.Uint64()
+		afEvalDepthBuckets[afEvalDepthBucket(depth)].timer.UpdateSince(start)
+	}
+	d := AFDecision{
+		CommonAncestor: commonAncestor,
+		Current:        current,
+		Proposed:       proposed,
+		Mechanism:      mechanism,
+		Err:            err,
+		Time:           time.Now(),
+	}
+	// Comparing the segments costs extra lookups, so only do so for the
+	// observer and the record of the last accepted reorg.
+	accepted := err == nil && mechanism == AFMechanismConsensus
+	if accepted || bc.afObserver.Load() != nil {
+		if cmp, err := bc.CompareSegments(commonAncestor, current, proposed); err == nil {
+			d.Comparison, d.Margin = cmp, cmp.Margin
+		}
+	}
+	if err != nil {
+		afRejectionMeter.Mark(1)
+		bc.afRejections.add(commonAncestor, current)
+	}
+	if accepted {
+		bc.afLastAccepted.Store(&ReorgDecision{CommonAncestor: commonAncestor, Current: current, Proposed: proposed, Margin: d.Margin, Time: d.Time})
+	}
+	bc.notifyArtificialFinalityObserver(d)
+	return err
+}
+
+// scoreArtificialFinality implements evaluateArtificialFinality, additionally
+// returning the mechanism that determined the outcome.
+func (bc *BlockChain) scoreArtificialFinality(commonAncestor, current, proposed *types.Header) (string, error) {
+	if target, ok := bc.whitelistedReorgTarget(commonAncestor, proposed); ok {
+		bc.afLogger.Warn("Bypassing artificial finality for whitelisted reorg target", "target", target,
+			"common.bno", commonAncestor.Number, "current.bno", current.Number, "current.hash", current.Hash(),
+			"proposed.bno", proposed.Number, "proposed.hash", proposed.Hash())
+		return AFMechanismWhitelist, nil
+	}
+	quarantine := AFRejectionMode(bc.afRejectionMode.Load()) == AFRejectionQuarantine
+	if quarantine && bc.afQuarantine.contains(proposed) {
+		// Keep extending the quarantine along the re-offered chain.
+		bc.afQuarantine.add(proposed.Hash())
+		return AFMechanismQuarantine, fmt.Errorf("%w: proposed.bno=%d proposed.hash=%s", errReorgFinalityQuarantined, proposed.Number.Uint64(), proposed.Hash().Hex())
+	}
+	bc.consensusScorersMu.RLock()
+	defer bc.consensusScorersMu.RUnlock()
+
+	// Every scorer is consulted so that all the mechanisms disallowing the
+	// reorg are reported, attributing the rejection to the first of them.
+	var (
+		mechanism string
+		rejection error
+	)
+	for _, scorer := range bc.consensusScorers {
+		err := scorer.ScoreReorg(commonAncestor, current, proposed)
+		if err == nil {
+			continue
+		}
+		if rejection == nil {
+			mechanism, rejection = consensusScorerName(scorer), err
+		} else {
+			rejection = fmt.Errorf("%w; %w", rejection, err)
+		}
+	}
+	if rejection == nil {
+		return AFMechanismConsensus, nil
+	}
+	if quarantine && errors.Is(rejection, errReorgFinality) {
+		bc.afQuarantine.add(proposed.Hash())
+	}
+	return mechanism, rejection
+}
+
+// consensusScorerName names a consensus scorer for AFDecision.Mechanism.
+func consensusScorerName(scorer ConsensusScorer) string {
+	if _, ok := scorer.(*messScorer); ok {
+		return AFMechanismMESS
+	}
+	return fmt.Sprintf("%T", scorer)
+}
+
+// Mechanisms reported in AFDecision.Mechanism. Rejections by a registered
+// consensus scorer other than MESS report the scorer's type.
+const (
+	AFMechanismWhitelist  = "whitelist"  // the proposed segment contains a whitelisted reorg target
+	AFMechanismQuarantine = "quarantine" // the proposed chain builds on a quarantined head
+	AFMechanismMESS       = "mess"       // rejected by ECBP1100 (MESS)
+	AFMechanismConsensus  = "consensus"  // allowed by every consensus scorer
+)
+
+// AFDecision is the outcome of an artificial finality evaluation, as passed to
+// the observer set with SetArtificialFinalityObserver.
+type AFDecision struct {
+	CommonAncestor *types.Header
+	Current        *types.Header
+	Proposed       *types.Header
+	Mechanism      string    // mechanism that determined the outcome
+	Err            error     // non-nil if the reorg was disallowed
+	Margin         float64   // ECBP1100 (MESS) margin, or 0 if it could not be computed
+	Time           time.Time // when the decision was made
+
+	Comparison *SegmentComparison // the segments compared, or nil if they could not be
+}
+
+// afObserverQueue is the number of decisions buffered for the observer.
+const afObserverQueue = 64
+
+// SetArtificialFinalityObserver sets a function called with every artificial
+// finality decision, accepted or rejected. Decisions are delivered in order on
+// a dedicated goroutine, outside of any chain lock, so the observer may call
+// back into the chain. Decisions are never waited on: once afObserverQueue of
+// them are pending, further ones are dropped until the observer catches up.
+// A nil fn removes the observer.
+func (bc *BlockChain) SetArtificialFinalityObserver(fn func(AFDecision)) {
+	if fn == nil {
+		bc.afObserver.Store(nil)
+		return
+	}
+	bc.afObserver.Store(&fn)
+}
+
+// notifyArtificialFinalityObserver queues the decision for the observer, if any.
+// It is called with the chain mutex held, so it must not block on the observer.
+func (bc *BlockChain) notifyArtificialFinalityObserver(d AFDecision) {
+	if bc.afObserver.Load() == nil {
+		return
+	}
+	select {
+	case bc.afDecisions <- d:
+	default:
+		afObserverDroppedMeter.Mark(1)
+	}
+}
+
+// afObserverLoop delivers queued decisions to the observer.
+func (bc *BlockChain) afObserverLoop() {
+	defer bc.wg.Done()
+	for {
+		select {
+		case d := <-bc.afDecisions:
+			if fn := bc.afObserver.Load(); fn != nil {
+				(*fn)(d)
+			}
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// AFTiming summarizes the time taken by artificial finality decisions, for all
+// of them or those of a segment depth bucket. Durations are in nanoseconds, and
+// all values are zero if metrics are disabled.
+type AFTiming struct {
+	Bucket   string  `json:"bucket"`
+	MaxDepth uint64  `json:"maxDepth,omitempty"` // deepest current segment in the bucket, unset for the last one
+	Count    int64   `json:"count"`
+	Mean     float64 `json:"mean"`
+	P50      float64 `json:"p50"`
+	P95      float64 `json:"p95"`
+	Max      int64   `json:"max"`
+}
+
+// ArtificialFinalityTimings returns the time taken by artificial finality
+// decisions, overall followed by each segment depth bucket.
+func (bc *BlockChain) ArtificialFinalityTimings() []AFTiming {
+	summarize := func(bucket string, maxDepth uint64, timer metrics.Timer) AFTiming {
+		snap := timer.Snapshot()
+		ps := snap.Percentiles([]float64{0.5, 0.95})
+		return AFTiming{Bucket: bucket, MaxDepth: maxDepth, Count: snap.Count(), Mean: snap.Mean(), P50: ps[0], P95: ps[1], Max: snap.Max()}
+	}
+	timings := []AFTiming{summarize("all", 0, afEvalTimer)}
+	for _, b := range afEvalDepthBuckets {
+		maxDepth := b.maxDepth
+		if maxDepth == math.MaxUint64 {
+			maxDepth = 0
+		}
+		timings = append(timings, summarize(b.name, maxDepth, b.timer))
+	}
+	return timings
+}
+
+// ReorgDecision is a snapshot of a reorg evaluated by artificial finality.
+type ReorgDecision struct {
+	CommonAncestor *types.Header
+	Current        *types.Header
+	Proposed       *types.Header
+	Margin         float64   // ECBP1100 (MESS) margin; 1 or above means allowed
+	Time           time.Time // when the decision was made
+}
+
+// LastAcceptedReorg returns the most recent reorg allowed after being evaluated
+// by artificial finality. Head extensions and whitelisted reorgs bypass the
+// evaluation and are not recorded.
+func (bc *BlockChain) LastAcceptedReorg() (*ReorgDecision, bool) {
+	d := bc.afLastAccepted.Load()
+	return d, d != nil
+}
+
+// AFRejectionMode determines how reorgs disallowed by artificial finality are
+// handled.
+type AFRejectionMode int32
+
+const (
+	// AFRejectionReject rejects the reorg, evaluating the proposed chain again
+	// when it is re-offered.
+	AFRejectionReject AFRejectionMode = iota
+	// AFRejectionQuarantine rejects the reorg and drops chains re-offered on top
+	// of the rejected head within a cooldown without evaluating them again.
+	AFRejectionQuarantine
+)
+
+func (m AFRejectionMode) String() string {
+	switch m {
+	case AFRejectionReject:
+		return "reject"
+	case AFRejectionQuarantine:
+		return "quarantine"
+	default:
+		return fmt.Sprintf("unknown(%d)", int32(m))
+	}
+}
+
+const (
+	afQuarantineLimit    = 1024
+	afQuarantineCooldown = 10 * time.Minute
+)
+
+// SetArtificialFinalityRejectionMode sets how reorgs disallowed by artificial
+// finality are handled. The default is AFRejectionReject.
+func (bc *BlockChain) SetArtificialFinalityRejectionMode(mode AFRejectionMode) {
+	bc.afRejectionMode.Store(int32(mode))
+}
+
+// afQuarantineSet holds the heads of recently rejected proposed chains, each
+// for the duration of a cooldown.
+type afQuarantineSet struct {
+	mu       sync.Mutex
+	items    lru.BasicLRU[common.Hash, time.Time]
+	cooldown time.Duration
+}
+
+func newAFQuarantineSet(limit int, cooldown time.Duration) *afQuarantineSet {
+	return &afQuarantineSet{items: lru.NewBasicLRU[common.Hash, time.Time](limit), cooldown: cooldown}
+}
+
+// add quarantines the given proposed head.
+func (q *afQuarantineSet) add(hash common.Hash) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.items.Add(hash, time.Now())
+}
+
+// contains reports whether the proposed header, or its parent, is quarantined.
+func (q *afQuarantineSet) contains(proposed *types.Header) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, hash := range []common.Hash{proposed.Hash(), proposed.ParentHash} {
+		added, ok := q.items.Get(hash)
+		if !ok {
+			continue
+		}
+		if time.Since(added) > q.cooldown {
+			q.items.Remove(hash)
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// afRejectionWindow is the number of recent rejected reorgs kept for
+// ReorgRejectionStats.
+const afRejectionWindow = 256
+
+// afRejection records the extent of a reorg disallowed by artificial finality.
+type afRejection struct {
+	age  uint64 // seconds from the common ancestor to the current head
+	span uint64 // blocks from the common ancestor to the current head
+}
+
+// afRejectionLog is a ring buffer of the most recent rejected reorgs.
+type afRejectionLog struct {
+	mu      sync.Mutex
+	entries []afRejection
+	next    int
+}
+
+func newAFRejectionLog(limit int) *afRejectionLog {
+	return &afRejectionLog{entries: make([]afRejection, 0, limit)}
+}
+
+// add records a rejected reorg, evicting the oldest one if the log is full.
+func (l *afRejectionLog) add(commonAncestor, current *types.Header) {
+	var r afRejection
+	if current.Time > commonAncestor.Time {
+		r.age = current.Time - commonAncestor.Time
+	}
+	if current.Number.Cmp(commonAncestor.Number) > 0 {
+		r.span = current.Number.Uint64() - commonAncestor.Number.Uint64()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, r)
+		return
+	}
+	l.entries[l.next] = r
+	l.next = (l.next + 1) % len(l.entries)
+}
+
+// RejectionStats summarizes the most recent reorgs disallowed by artificial
+// finality. Ages are in seconds, from the common ancestor to the current head.
+type RejectionStats struct {
+	Count    int    `json:"count"`
+	AgeP50   uint64 `json:"ageP50"`
+	AgeP90   uint64 `json:"ageP90"`
+	AgeP99   uint64 `json:"ageP99"`
+	MaxSpan  uint64 `json:"maxSpan"` // most blocks from the common ancestor to the current head
+	Capacity int    `json:"capacity"`
+}
+
+// ReorgRejectionStats returns statistics on the reorgs most recently disallowed
+// by artificial finality, up to the last afRejectionWindow of them.
+func (bc *BlockChain) ReorgRejectionStats() RejectionStats {
+	l := bc.afRejections
+	l.mu.Lock()
+	ages := make([]uint64, len(l.entries))
+	stats := RejectionStats{Count: len(l.entries), Capacity: cap(l.entries)}
+	for i, r := range l.entries {
+		ages[i] = r.age
+		if r.span > stats.MaxSpan {
+			stats.MaxSpan = r.span
+		}
+	}
+	l.mu.Unlock()
+
+	if len(ages) == 0 {
+		return stats
+	}
+	sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+	// Nearest-rank percentile.
+	percentile := func(p int) uint64 {
+		rank := (p*len(ages) + 99) / 100
+		return ages[rank-1]
+	}
+	stats.AgeP50, stats.AgeP90, stats.AgeP99 = percentile(50), percentile(90), percentile(99)
+	return stats
+}
+
+// DefaultAFRejectionLogInterval is the default minimum interval between
+// warnings with the same message about reorgs with the same common ancestor.
+const DefaultAFRejectionLogInterval = 10 * time.Second
+
+// afLogLimiterSize is the number of message and common ancestor pairs whose
+// warnings are rate limited at any time.
+const afLogLimiterSize = 256
+
+type afLogKey struct {
+	msg      string
+	ancestor common.Hash
+}
+
+type afLogEntry struct {
+	last       time.Time
+	suppressed uint64
+}
+
+// afLogLimiter rate limits artificial finality warnings, so a flood of
+// competing blocks forking at the same common ancestor doesn't drown the logs.
+type afLogLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	entries  lru.BasicLRU[afLogKey, *afLogEntry]
+}
+
+func newAFLogLimiter(interval time.Duration) *afLogLimiter {
+	return &afLogLimiter{interval: interval, entries: lru.NewBasicLRU[afLogKey, *afLogEntry](afLogLimiterSize)}
+}
+
+// allow reports whether a warning with the given message about a reorg forking
+// at ancestor may be logged at the given time, along with the number of such
+// warnings suppressed since the last one logged.
+func (l *afLogLimiter) allow(msg string, ancestor common.Hash, now time.Time) (bool, uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.interval <= 0 {
+		return true, 0
+	}
+	key := afLogKey{msg, ancestor}
+	entry, ok := l.entries.Get(key)
+	if !ok {
+		l.entries.Add(key, &afLogEntry{last: now})
+		return true, 0
+	}
+	if now.Sub(entry.last) < l.interval {
+		entry.suppressed++
+		return false, 0
+	}
+	suppressed := entry.suppressed
+	entry.last, entry.suppressed = now, 0
+	return true, suppressed
+}
+
+// SetArtificialFinalityRejectionLogInterval sets the minimum interval between
+// warnings with the same message about reorgs with the same common ancestor.
+// Suppressed warnings are counted in the next one logged. Zero or less logs
+// every warning.
+func (bc *BlockChain) SetArtificialFinalityRejectionLogInterval(interval time.Duration) {
+	bc.afLogLimiter.mu.Lock()
+	defer bc.afLogLimiter.mu.Unlock()
+
+	bc.afLogLimiter.interval = interval
+}
+
+// afLimitedLogger returns a logger for the decision of a reorg forking at
+// commonAncestor, which may be nil if unknown, writing to afLogger subject to
+// the rejection log interval for warnings and above.
+func (bc *BlockChain) afLimitedLogger(commonAncestor *types.Header) log.Logger {
+	var ancestor common.Hash
+	if commonAncestor != nil {
+		ancestor = commonAncestor.Hash()
+	}
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Lvl <= log.LvlWarn {
+			ok, suppressed := bc.afLogLimiter.allow(r.Msg, ancestor, r.Time)
+			if !ok {
+				return nil
+			}
+			if suppressed > 0 {
+				r.Ctx = append(r.Ctx, "suppressed", suppressed)
+			}
+		}
+		return bc.afLogger.GetHandler().Log(r)
+	}))
+	return logger
+}
+
+// WarmReorgCaches loads the headers and total difficulties of the chains ending
+// at currentHash and proposedHash, back to their common ancestor, into the
+// header chain caches. It is meant to be called speculatively when a competing
+// head is first observed, so that the artificial finality evaluation of the
+// reorg doesn't have to go to the database. Unknown blocks are ignored.
+func (bc *BlockChain) WarmReorgCaches(currentHash, proposedHash common.Hash) {
+	current, proposed := bc.GetHeaderByHash(currentHash), bc.GetHeaderByHash(proposedHash)
+	for current != nil && proposed != nil && current.Hash() != proposed.Hash() {
+		// Step back the higher chain, or both if at the same height.
+		currentNum, proposedNum := current.Number.Uint64(), proposed.Number.Uint64()
+		if currentNum == 0 || proposedNum == 0 {
+			return
+		}
+		if currentNum >= proposedNum {
+			bc.GetTd(current.Hash(), currentNum)
+			current = bc.GetHeader(current.ParentHash, currentNum-1)
+		}
+		if proposedNum >= currentNum {
+			bc.GetTd(proposed.Hash(), proposedNum)
+			proposed = bc.GetHeader(proposed.ParentHash, proposedNum-1)
+		}
+	}
+	if current != nil {
+		bc.GetTd(current.Hash(), current.Number.Uint64())
+	}
+}
+
+// MESSMargin returns how close a reorg from current to proposed, forking at
+// commonAncestor, is to the ECBP1100 (MESS) threshold: the ratio of the
+// proposed subchain TD to the TD required by the curve. A margin of at least 1
+// means the reorg is allowed, below 1 that it is rejected.
+func (bc *BlockChain) MESSMargin(commonAncestor, current, proposed *types.Header) (margin float64, err error) {
+	ops, err := ecbp1100Operands(messCurvePolynomialV, commonAncestor, current, proposed, bc.GetTd)
+	if err != nil {
+		return 0, err
+	}
+	return ops.margin(), nil
+}
+
+// MESSDecision describes the ECBP1100 (MESS) evaluation of a reorg from the
+// current head to a proposed block.
+type MESSDecision struct {
+	CommonAncestor *types.Header
+	Current        *types.Header
+	Proposed       *types.Header
+
+	Reorg    bool  // false if the proposed block is already part of the canonical chain
+	Rejected error // non-nil if MESS would disallow the reorg
+
+	Age                uint64   // seconds between the common ancestor and the current head
+	CurveValue         *big.Int // curve numerator at Age, over CURVE_FUNCTION_DENOMINATOR
+	CurveDenominator   *big.Int
+	LocalSubchainTD    *big.Int
+	ProposedSubchainTD *big.Int
+}
+
+// MESSDecision evaluates ECBP1100 (MESS) for a reorg from the current head to
+// the stored block with the given hash, without modifying the chain. The
+// evaluation ignores whether artificial finality is enabled or activated.
+func (bc *BlockChain) MESSDecision(hash common.Hash) (*MESSDecision, error) {
+	proposed := bc.GetHeaderByHash(hash)
+	if proposed == nil {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	return bc.MESSDecisionAt(bc.CurrentHeader(), proposed)
+}
+
+// MESSDecisionAt is MESSDecision for a reorg from the given head, which need
+// not be the current one, such as a past canonical block when replaying history.
+func (bc *BlockChain) MESSDecisionAt(current, proposed *types.Header) (*MESSDecision, error) {
+	d := &MESSDecision{Current: current, Proposed: proposed}
+
+	// A canonical block at or below a canonical head is trivially not a reorg.
+	if proposed.Number.Uint64() <= current.Number.Uint64() &&
+		bc.GetCanonicalHash(current.Number.Uint64()) == current.Hash() &&
+		bc.GetCanonicalHash(proposed.Number.Uint64()) == proposed.Hash() {
+		d.CommonAncestor = proposed
+		return d, nil
+	}
+	d.Reorg = true
+	ancestor, err := bc.CommonAncestor(current, proposed)
+	if err != nil {
+		return nil, err
+	}
+	d.CommonAncestor = ancestor
+	ops, err := ecbp1100Operands(messCurvePolynomialV, d.CommonAncestor, current, proposed, bc.GetTd)
+	if err != nil {
+		return nil, err
+	}
+	d.Age = ops.age.Uint64()
+	d.CurveValue = messCurvePolynomialV.numerator(ops.age)
+	d.CurveDenominator = new(big.Int).Set(messCurvePolynomialV.denominator)
+	d.LocalSubchainTD = ops.localSubchainTD
+	d.ProposedSubchainTD = ops.proposedSubchainTD
+	// The evaluation is hypothetical, so don't log it as a decision.
+	logger := log.New()
+	logger.SetHandler(log.DiscardHandler())
+	d.Rejected = ecbp1100(logger, bc.chainConfig, d.CommonAncestor, current, proposed, bc.GetTd, bc.GetHeader)
+	return d, nil
+}
+
+// CommonAncestor returns the fork point of the chains ending at a and b,
+// walking both back through the header lookups. It errors if no common
+// ancestor is found within the available headers.
+func (bc *BlockChain) CommonAncestor(a, b *types.Header) (*types.Header, error) {
+	ancestor, err := bc.forker.CommonAncestor(a, b)
+	if err != nil {
+		return nil, fmt.Errorf("no common ancestor between %x and %x: %w", a.Hash(), b.Hash(), err)
+	}
+	return ancestor, nil
+}
+
+// MESSCurveEvaluation is the ECBP1100 (MESS) decision for a reorg under one of
+// the candidate curves.
+type MESSCurveEvaluation struct {
+	Curve    string
+	Accepted bool
+	Margin   float64 // ratio of the proposed subchain TD to the TD required by the curve
+}
+
+// messComparisonCurves are the candidate ECBP1100 (MESS) curves discussed in
+// ECIP-374, as anti-gravity functions of the common ancestor age in seconds.
+var messComparisonCurves = []struct {
+	name        string
+	antiGravity func(age uint64) float64
+}{
+	{messCurvePolynomialV.name, messCurvePolynomialV.antiGravity},
+	{messCurveSinusoidalV.name, messCurveSinusoidalV.antiGravity},
+	{"expA", func(age uint64) float64 { return ecbp1100AGExpA(float64(age)) }},
+	{"expB", func(age uint64) float64 { return ecbp1100AGExpB(float64(age)) }},
+}
+
+// MESSCurveComparison evaluates a reorg from the current head to the stored
+// block with the given hash under each candidate ECBP1100 (MESS) curve, without
+// modifying the chain. The comparisons are made in floating point, so they may
+// differ from the consensus decision on an exact tie.
+func (bc *BlockChain) MESSCurveComparison(hash common.Hash) ([]MESSCurveEvaluation, error) {
+	d, err := bc.MESSDecision(hash)
+	if err != nil {
+		return nil, err
+	}
+	if !d.Reorg || d.LocalSubchainTD.Sign() == 0 {
+		return nil, fmt.Errorf("block %x is not a reorg of the current head", hash)
+	}
+	tdRatio, _ := new(big.Float).Quo(new(big.Float).SetInt(d.ProposedSubchainTD), new(big.Float).SetInt(d.LocalSubchainTD)).Float64()
+
+	evals := make([]MESSCurveEvaluation, len(messComparisonCurves))
+	for i, curve := range messComparisonCurves {
+		margin := tdRatio / curve.antiGravity(d.Age)
+		evals[i] = MESSCurveEvaluation{Curve: curve.name, Accepted: margin >= 1, Margin: margin}
+	}
+	return evals, nil
+}
+
+// afTraceLimit is the maximum number of blocks of each segment included in an
+// artificial finality decision trace.
+const afTraceLimit = 10_000
+
+// AFTraceHeader is a block visited while tracing an artificial finality decision.
+type AFTraceHeader struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	Time       hexutil.Uint64 `json:"timestamp"`
+	Difficulty *hexutil.Big   `json:"difficulty"`
+	TD         *hexutil.Big   `json:"totalDifficulty,omitempty"` // unset if not stored
+}
+
+// AFTrace is the complete ECBP1100 (MESS) evaluation of a reorg: the segments
+// compared, the sums taken over them and the operands of the comparison.
+type AFTrace struct {
+	CommonAncestor AFTraceHeader   `json:"commonAncestor"`
+	Current        []AFTraceHeader `json:"current"`  // from the common ancestor (exclusive) to the current head
+	Proposed       []AFTraceHeader `json:"proposed"` // from the common ancestor (exclusive) to the proposed head
+
+	Reorg    bool   `json:"reorg"` // false if the proposed block is already part of the canonical chain
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"`
+
+	Age                hexutil.Uint64 `json:"age"`
+	CurveValue         *hexutil.Big   `json:"curveValue,omitempty"`
+	CurveDenominator   *hexutil.Big   `json:"curveDenominator,omitempty"`
+	LocalSubchainTD    *hexutil.Big   `json:"localSubchainTD,omitempty"`
+	ProposedSubchainTD *hexutil.Big   `json:"proposedSubchainTD,omitempty"`
+	Want               *hexutil.Big   `json:"want,omitempty"` // curveValue * localSubchainTD
+	Got                *hexutil.Big   `json:"got,omitempty"`  // proposedSubchainTD * curveDenominator
+	Margin             float64        `json:"margin,omitempty"`
+}
+
+// TraceAFDecision traces the ECBP1100 (MESS) decision for a reorg from the
+// stored block currentHash to the stored block proposedHash, returning it as
+// indented JSON. The evaluation ignores whether artificial finality is enabled
+// or activated, and doesn't modify the chain.
+func (bc *BlockChain) TraceAFDecision(currentHash, proposedHash common.Hash) ([]byte, error) {
+	current := bc.GetHeaderByHash(currentHash)
+	if current == nil {
+		return nil, fmt.Errorf("block %x not found", currentHash)
+	}
+	proposed := bc.GetHeaderByHash(proposedHash)
+	if proposed == nil {
+		return nil, fmt.Errorf("block %x not found", proposedHash)
+	}
+	d, err := bc.MESSDecisionAt(current, proposed)
+	if err != nil {
+		return nil, err
+	}
+	trace := &AFTrace{
+		CommonAncestor: bc.afTraceHeader(d.CommonAncestor),
+		Current:        []AFTraceHeader{},
+		Proposed:       []AFTraceHeader{},
+		Reorg:          d.Reorg,
+		Accepted:       d.Rejected == nil,
+		Age:            hexutil.Uint64(d.Age),
+	}
+	if d.Rejected != nil {
+		trace.Reason = d.Rejected.Error()
+	}
+	if d.Reorg {
+		if trace.Current, err = bc.afTraceSegment(d.CommonAncestor, current); err != nil {
+			return nil, err
+		}
+		if trace.Proposed, err = bc.afTraceSegment(d.CommonAncestor, proposed); err != nil {
+			return nil, err
+		}
+		trace.CurveValue = (*hexutil.Big)(d.CurveValue)
+		trace.CurveDenominator = (*hexutil.Big)(d.CurveDenominator)
+		trace.LocalSubchainTD = (*hexutil.Big)(d.LocalSubchainTD)
+		trace.ProposedSubchainTD = (*hexutil.Big)(d.ProposedSubchainTD)
+		trace.Want = (*hexutil.Big)(new(big.Int).Mul(d.CurveValue, d.LocalSubchainTD))
+		trace.Got = (*hexutil.Big)(new(big.Int).Mul(d.ProposedSubchainTD, d.CurveDenominator))
+		if margin, err := bc.MESSMargin(d.CommonAncestor, current, proposed); err == nil {
+			trace.Margin = margin
+		}
+	}
+	return json.MarshalIndent(trace, "", "  ")
+}
+
+// afTraceHeader describes the given header for an artificial finality trace.
+func (bc *BlockChain) afTraceHeader(header *types.Header) AFTraceHeader {
+	h := AFTraceHeader{
+		Number:     hexutil.Uint64(header.Number.Uint64()),
+		Hash:       header.Hash(),
+		Time:       hexutil.Uint64(header.Time),
+		Difficulty: (*hexutil.Big)(header.Difficulty),
+	}
+	if td := bc.GetTd(h.Hash, uint64(h.Number)); td != nil {
+		h.TD = (*hexutil.Big)(td)
+	}
+	return h
+}
+
+// afTraceSegment walks back from head to the common ancestor, returning the
+// blocks in between in ascending order.
+func (bc *BlockChain) afTraceSegment(commonAncestor, head *types.Header) ([]AFTraceHeader, error) {
+	if head.Number.Cmp(commonAncestor.Number) <= 0 {
+		return []AFTraceHeader{}, nil
+	}
+	n := head.Number.Uint64() - commonAncestor.Number.Uint64()
+	if n > afTraceLimit {
+		return nil, fmt.Errorf("segment of %d blocks exceeds the trace limit of %d", n, afTraceLimit)
+	}
+	segment := make([]AFTraceHeader, n)
+	for h := head; n > 0; n-- {
+		segment[n-1] = bc.afTraceHeader(h)
+		if n == 1 {
+			break
+		}
+		if h = bc.GetHeader(h.ParentHash, h.Number.Uint64()-1); h == nil {
+			return nil, fmt.Errorf("missing ancestor of block %x", segment[n-1].Hash)
+		}
+	}
+	return segment, nil
+}
+
+// MESSMarginWarnThreshold returns the MESS margin below which accepted reorgs
+// are reported, as set by the afWarnMargin chain config.
+func (bc *BlockChain) MESSMarginWarnThreshold() float64 {
+	return messMarginWarnThreshold(bc.chainConfig)
+}
+
+// AFConfig is a snapshot of the artificial finality settings in effect.
+type AFConfig struct {
+	Enabled   bool `json:"enabled"`   // operational toggle, see EnableArtificialFinality
+	NoDisable bool `json:"noDisable"` // set if artificial finality can't be disabled once enabled
+	Active    bool `json:"active"`    // set if ECBP1100 is activated by the chain config at the current head
+
+	InactiveReason string `json:"inactiveReason,omitempty"` // set if enabled but not active, see ArtificialFinalityInactiveReason
+
+	ECBP1100Transition           *uint64                  `json:"ecbp1100Transition"`
+	ECBP1100DeactivateTransition *uint64                  `json:"ecbp1100DeactivateTransition"`
+	Curve                        string                   `json:"curve"`
+	MaxAge                       *uint64                  `json:"maxAge"`
+	TieBreak                     ctypes.ECBP1100TieBreakT `json:"tieBreak"`
+
+	MarginWarnThreshold float64       `json:"marginWarnThreshold"`
+	LogLevel            string        `json:"logLevel"` // "root" if following the global verbosity
+	ReorgWhitelist      []common.Hash `json:"reorgWhitelist"`
+	RejectionMode       string        `json:"rejectionMode"`
+}
+
+// ArtificialFinalityConfig returns a snapshot of the artificial finality
+// settings in effect, from both the chain config and the operator.
+func (bc *BlockChain) ArtificialFinalityConfig() AFConfig {
+	bc.artificialFinalityMu.Lock()
+	noDisable := bc.artificialFinalityNoDisable != nil && atomic.LoadInt32(bc.artificialFinalityNoDisable) == 1
+	bc.artificialFinalityMu.Unlock()
+
+	cfg := AFConfig{
+		Enabled:                      bc.IsArtificialFinalityEnabled(),
+		NoDisable:                    noDisable,
+		ECBP1100Transition:           bc.chainConfig.GetECBP1100Transition(),
+		ECBP1100DeactivateTransition: bc.chainConfig.GetECBP1100DeactivateTransition(),
+		Curve:                        messCurvePolynomialV.name,
+		MaxAge:                       bc.chainConfig.GetECBP1100MaxAge(),
+		MarginWarnThreshold:          bc.MESSMarginWarnThreshold(),
+		LogLevel:                     "root",
+		ReorgWhitelist:               bc.ReorgWhitelist(),
+		RejectionMode:                AFRejectionMode(bc.afRejectionMode.Load()).String(),
+		InactiveReason:               bc.ArtificialFinalityInactiveReason(),
+	}
+	if head := bc.CurrentHeader(); head != nil {
+		cfg.Active = bc.chainConfig.IsEnabled(bc.chainConfig.GetECBP1100Transition, head.Number)
+	}
+	if tb := bc.chainConfig.GetECBP1100TieBreak(); tb != nil {
+		cfg.TieBreak = *tb
+	}
+	if lvl := bc.afLogLevel.Load(); lvl >= 0 {
+		cfg.LogLevel = log.Lvl(lvl).String()
+	}
+	return cfg
+}
+
 // getTDRatio is a helper function returning the total difficulty ratio of
 // proposed over current chain segments.
 // nolint:unused
@@ -112,42 +1294,348 @@ func (bc *BlockChain) getTDRatio(commonAncestor, current, proposed *types.Header
 // ecbp1100 implements the "MESS" artificial finality mechanism
 // "Modified Exponential Subjective Scoring" used to prefer known chain segments
 // over later-to-come counterparts, especially proposed segments stretching far into the past.
-func ecbp1100(commonAncestor, current, proposed *types.Header, getTDFunc func(common.Hash, uint64) *big.Int) error {
-	// Get the total difficulties of the proposed chain segment and the existing one.
-	commonAncestorTD := getTDFunc(commonAncestor.Hash(), commonAncestor.Number.Uint64())
-	proposedParentTD := getTDFunc(proposed.ParentHash, proposed.Number.Uint64()-1)
-	proposedTD := new(big.Int).Add(proposed.Difficulty, proposedParentTD)
-	localTD := getTDFunc(current.Hash(), current.Number.Uint64())
-
-	// if proposed_subchain_td * CURVE_FUNCTION_DENOMINATOR < get_curve_function_numerator(proposed.Time - commonAncestor.Time) * local_subchain_td.
-	proposedSubchainTD := new(big.Int).Sub(proposedTD, commonAncestorTD)
-	localSubchainTD := new(big.Int).Sub(localTD, commonAncestorTD)
-
-	xBig := big.NewInt(int64(current.Time - commonAncestor.Time))
-	eq := ecbp1100PolynomialV(xBig)
-	want := eq.Mul(eq, localSubchainTD)
-
-	got := new(big.Int).Mul(proposedSubchainTD, ecbp1100PolynomialVCurveFunctionDenominator)
+//
+// If the config sets a maximum age, reorgs whose common ancestor is older than
+// that (relative to the current head) are rejected without evaluating the curve.
+// Reorgs replacing no more than the tip grace of blocks are exempt from the
+// curve, though not from the maximum age and difficulty checks.
+// Rejected reorgs are logged to logger at warn level with their operands as
+// discrete fields, allowed ones at debug level.
+func ecbp1100(logger log.Logger, config ctypes.ChainConfigurator, commonAncestor, current, proposed *types.Header, getTDFunc func(common.Hash, uint64) *big.Int, getHeaderFunc func(common.Hash, uint64) *types.Header) error {
+	return ecbp1100WithCurve(logger, config, messCurvePolynomialV, commonAncestor, current, proposed, getTDFunc, getHeaderFunc, nil)
+}
 
-	if got.Cmp(want) < 0 {
-		prettyRatio, _ := new(big.Float).Quo(
-			new(big.Float).SetInt(got),
-			new(big.Float).SetInt(want),
-		).Float64()
+// ecbp1100WithCurve is ecbp1100 evaluated against the given curve. If
+// comparison is non-nil, it is filled with the segments compared against the
+// curve, whether the reorg is then allowed or not. Reorgs rejected before the
+// curve is evaluated, or exempt at the tip, leave it untouched.
+func ecbp1100WithCurve(logger log.Logger, config ctypes.ChainConfigurator, curve *messCurve, commonAncestor, current, proposed *types.Header, getTDFunc func(common.Hash, uint64) *big.Int, getHeaderFunc func(common.Hash, uint64) *types.Header, comparison *SegmentComparison) error {
+	// The sanity checks describe their rejection in the error alone; log them
+	// like the other rejections so the caller need not.
+	rejectSanity := func(err error) error {
+		if errors.Is(err, errReorgFinalityMESS) {
+			logger.Warn("ECBP1100-MESS 🔒 rejected",
+				"reason", "sanity", "err", err,
+				"common_bno", commonAncestor.Number.Uint64(), "common_hash", commonAncestor.Hash(),
+				"current_bno", current.Number.Uint64(), "current_hash", current.Hash(),
+				"proposed_bno", proposed.Number.Uint64(), "proposed_hash", proposed.Hash(),
+			)
+		}
+		return err
+	}
+	if err := ecbp1100CheckAge(commonAncestor, current); err != nil {
+		return rejectSanity(err)
+	}
+	if maxAge := config.GetECBP1100MaxAge(); maxAge != nil && *maxAge > 0 && current.Time-commonAncestor.Time > *maxAge {
+		logger.Warn("ECBP1100-MESS 🔒 rejected",
+			"reason", "max-age",
+			"age", current.Time-commonAncestor.Time,
+			"max_age", *maxAge,
+			"common_bno", commonAncestor.Number.Uint64(), "common_hash", commonAncestor.Hash(),
+			"current_bno", current.Number.Uint64(), "current_hash", current.Hash(),
+			"proposed_bno", proposed.Number.Uint64(), "proposed_hash", proposed.Hash(),
+		)
+		return fmt.Errorf(`%w: ECBP1100-MESS 🔒 status=rejected reason=max-age age=%v max.age=%v common.bno=%d common.hash=%s current.bno=%d current.hash=%s proposed.bno=%d proposed.hash=%s`,
+			errReorgFinalityMESS,
+			common.PrettyDuration(time.Duration(current.Time-commonAncestor.Time)*time.Second),
+			common.PrettyDuration(time.Duration(*maxAge)*time.Second),
+			commonAncestor.Number.Uint64(), commonAncestor.Hash().Hex(),
+			current.Number.Uint64(), current.Hash().Hex(),
+			proposed.Number.Uint64(), proposed.Hash().Hex(),
+		)
+	}
+	ops, err := ecbp1100Operands(curve, commonAncestor, current, proposed, getTDFunc)
+	if err != nil {
+		return rejectSanity(err)
+	}
+	if err := ecbp1100CheckDifficulty(commonAncestor, proposed, ops, getHeaderFunc); err != nil {
+		return rejectSanity(err)
+	}
+	// Shallow reorgs at the tip pass the sanity checks above, but not the curve.
+	if current.Number.Cmp(commonAncestor.Number) >= 0 && current.Number.Uint64()-commonAncestor.Number.Uint64() <= messTipGrace(config) {
+		logger.Debug("ECBP1100-MESS 🔓 exempt tip reorg", "grace", messTipGrace(config),
+			"common.bno", commonAncestor.Number.Uint64(), "current.bno", current.Number.Uint64(), "proposed.bno", proposed.Number.Uint64())
+		return nil
+	}
+	if comparison != nil {
+		*comparison = *newSegmentComparison(commonAncestor, current, proposed, ops, getTDFunc)
+	}
+	// By default an exact tie allows the reorg; the config may favor the incumbent instead.
+	cmp := ops.got.Cmp(ops.want)
+	if tb := config.GetECBP1100TieBreak(); tb != nil && *tb == ctypes.ECBP1100TieBreak_FavorIncumbent && cmp == 0 {
+		cmp = -1
+	}
+	if cmp < 0 {
+		// Log the operands as discrete fields for structured log handlers; the
+		// returned error carries the same values in human readable form.
+		logger.Warn("ECBP1100-MESS 🔒 rejected",
+			"age", ops.age,
+			"current_span", current.Time-commonAncestor.Time,
+			"proposed_span", proposed.Time-commonAncestor.Time,
+			"ratio", ops.margin(),
+			"common_bno", commonAncestor.Number.Uint64(), "common_hash", commonAncestor.Hash(),
+			"current_bno", current.Number.Uint64(), "current_hash", current.Hash(),
+			"proposed_bno", proposed.Number.Uint64(), "proposed_hash", proposed.Hash(),
+		)
 		return fmt.Errorf(`%w: ECBP1100-MESS 🔒 status=rejected age=%v current.span=%v proposed.span=%v tdr/gravity=%0.6f common.bno=%d common.hash=%s current.bno=%d current.hash=%s proposed.bno=%d proposed.hash=%s`,
-			errReorgFinality,
+			errReorgFinalityMESS,
 			common.PrettyAge(time.Unix(int64(commonAncestor.Time), 0)),
 			common.PrettyDuration(time.Duration(current.Time-commonAncestor.Time)*time.Second),
-			common.PrettyDuration(time.Duration(int32(xBig.Uint64()))*time.Second),
-			prettyRatio,
+			common.PrettyDuration(time.Duration(int32(ops.age.Uint64()))*time.Second),
+			ops.margin(),
 			commonAncestor.Number.Uint64(), commonAncestor.Hash().Hex(),
 			current.Number.Uint64(), current.Hash().Hex(),
 			proposed.Number.Uint64(), proposed.Hash().Hex(),
 		)
 	}
+	// Leave a trail of accepted reorgs that came close to being rejected.
+	if warn, margin := messMarginWarnThreshold(config), ops.margin(); margin < warn {
+		logger.Warn("ECBP1100-MESS 🔓 allowed near threshold",
+			"got", ops.got, "want", ops.want, "ratio", margin, "warn.margin", warn,
+			"age.seconds", ops.age,
+			"local.subchain.td", ops.localSubchainTD, "proposed.subchain.td", ops.proposedSubchainTD,
+			"common.bno", commonAncestor.Number.Uint64(), "common.hash", commonAncestor.Hash(),
+			"current.bno", current.Number.Uint64(), "current.hash", current.Hash(),
+			"proposed.bno", proposed.Number.Uint64(), "proposed.hash", proposed.Hash(),
+		)
+	}
+	// Only the ratio is deferred; the remaining operands have already been
+	// computed for the comparison above.
+	logger.Debug("ECBP1100-MESS 🔓 allowed",
+		"age.seconds", ops.age,
+		"local.subchain.td", ops.localSubchainTD, "proposed.subchain.td", ops.proposedSubchainTD,
+		"got", ops.got, "want", ops.want,
+		"tdr/gravity", log.Lazy{Fn: ops.margin},
+		"common.bno", commonAncestor.Number.Uint64(),
+		"current.bno", current.Number.Uint64(),
+		"proposed.bno", proposed.Number.Uint64(),
+	)
 	return nil
 }
 
+// messCurve is an integer ECBP1100 (MESS) curve. A reorg is allowed if the
+// proposed subchain TD is at least numerator(age)/denominator times the local
+// one, so every curve carries the denominator its numerator is scaled by.
+type messCurve struct {
+	name        string
+	numerator   func(age *big.Int) *big.Int
+	denominator *big.Int
+}
+
+var (
+	messCurvePolynomialV = &messCurve{"polynomialV", ecbp1100PolynomialV, ecbp1100PolynomialVCurveFunctionDenominator}
+	messCurveSinusoidalV = &messCurve{"sinusoidalV", ecbp1100SinusoidalV, ecbp1100PolynomialVCurveFunctionDenominator}
+)
+
+// antiGravity returns numerator(age)/denominator as a float, for comparing the
+// curve with the floating point candidates of messComparisonCurves.
+func (c *messCurve) antiGravity(age uint64) float64 {
+	ag, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(c.numerator(new(big.Int).SetUint64(age))),
+		new(big.Float).SetInt(c.denominator),
+	).Float64()
+	return ag
+}
+
+// messOperands holds the operands of the ECBP1100 (MESS) comparison.
+type messOperands struct {
+	age                *big.Int // seconds between the common ancestor and the current head
+	localSubchainTD    *big.Int
+	proposedSubchainTD *big.Int
+	got                *big.Int // proposed_subchain_td * curve.denominator
+	want               *big.Int // curve.numerator(age) * local_subchain_td
+}
+
+// margin returns got/want; values below 1 mean the reorg is rejected.
+func (o *messOperands) margin() float64 {
+	// A zero local subchain TD would make the quotient undefined.
+	if o.want.Sign() == 0 {
+		if o.got.Sign() == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	margin, _ := new(big.Float).Quo(new(big.Float).SetInt(o.got), new(big.Float).SetInt(o.want)).Float64()
+	return margin
+}
+
+// SegmentHead is one end of a segment compared by artificial finality.
+type SegmentHead struct {
+	Number uint64
+	Hash   common.Hash
+	Time   uint64
+	TD     *big.Int
+}
+
+func newSegmentHead(header *types.Header, td *big.Int) SegmentHead {
+	return SegmentHead{Number: header.Number.Uint64(), Hash: header.Hash(), Time: header.Time, TD: td}
+}
+
+// SegmentComparison is the comparison of the local and proposed segments of a
+// reorg by ECBP1100 (MESS), in the typed form of the fields summarized by its
+// rejection errors.
+type SegmentComparison struct {
+	CommonAncestor SegmentHead
+	Current        SegmentHead
+	Proposed       SegmentHead
+
+	CurrentSpan  uint64 // seconds from the common ancestor to the current head
+	ProposedSpan uint64 // seconds from the common ancestor to the proposed head, 0 if older
+
+	LocalSubchainTD    *big.Int
+	ProposedSubchainTD *big.Int
+	Margin             float64 // 1 or above means the reorg is allowed, barring a tie break
+}
+
+// newSegmentComparison describes the segments of a reorg from the operands of
+// its ECBP1100 (MESS) comparison.
+func newSegmentComparison(commonAncestor, current, proposed *types.Header, ops *messOperands, getTDFunc func(common.Hash, uint64) *big.Int) *SegmentComparison {
+	commonAncestorTD := getTDFunc(commonAncestor.Hash(), commonAncestor.Number.Uint64())
+	cmp := &SegmentComparison{
+		CommonAncestor:     newSegmentHead(commonAncestor, commonAncestorTD),
+		Current:            newSegmentHead(current, new(big.Int).Add(commonAncestorTD, ops.localSubchainTD)),
+		Proposed:           newSegmentHead(proposed, new(big.Int).Add(commonAncestorTD, ops.proposedSubchainTD)),
+		CurrentSpan:        ops.age.Uint64(),
+		LocalSubchainTD:    ops.localSubchainTD,
+		ProposedSubchainTD: ops.proposedSubchainTD,
+		Margin:             ops.margin(),
+	}
+	if proposed.Time > commonAncestor.Time {
+		cmp.ProposedSpan = proposed.Time - commonAncestor.Time
+	}
+	return cmp
+}
+
+// CompareSegments compares the segments of a reorg from current to proposed,
+// forking at commonAncestor, as ECBP1100 (MESS) does, without deciding it.
+func (bc *BlockChain) CompareSegments(commonAncestor, current, proposed *types.Header) (*SegmentComparison, error) {
+	ops, err := ecbp1100Operands(messCurvePolynomialV, commonAncestor, current, proposed, bc.GetTd)
+	if err != nil {
+		return nil, err
+	}
+	return newSegmentComparison(commonAncestor, current, proposed, ops, bc.GetTd), nil
+}
+
+// ecbp1100CheckAge rejects a reorg if the current head is older than the
+// common ancestor, which can only result from corrupted data. The unsigned
+// age would otherwise wrap around and be fed to the curve.
+func ecbp1100CheckAge(commonAncestor, current *types.Header) error {
+	if current.Time < commonAncestor.Time {
+		return fmt.Errorf(`%w: ECBP1100-MESS 🔒 status=rejected reason=timestamp-inversion common.time=%d current.time=%d common.bno=%d common.hash=%s current.bno=%d current.hash=%s`,
+			errReorgFinalityMESS,
+			commonAncestor.Time, current.Time,
+			commonAncestor.Number.Uint64(), commonAncestor.Hash().Hex(),
+			current.Number.Uint64(), current.Hash().Hex(),
+		)
+	}
+	return nil
+}
+
+// ecbp1100DifficultyBand bounds the factor by which proposed block difficulties
+// may plausibly differ from the common ancestor's.
+const ecbp1100DifficultyBand = 1024
+
+// ecbp1100CheckDifficulty rejects a reorg whose proposed segment carries
+// implausible difficulties relative to the common ancestor: a zero-difficulty
+// block, or a block or per-block average difficulty outside
+// ecbp1100DifficultyBand. The proposed segment is walked back to the common
+// ancestor through getHeaderFunc, so that an implausible block can't hide
+// behind the average; headers missing from the lookup can't be checked, but
+// then neither can their total difficulty be trusted by the TD math.
+// Header verification should already rule these out, so this is only a
+// defense-in-depth guard against feeding corrupt values into the TD math.
+// Without a non-zero ancestor difficulty there is no reference to check against.
+func ecbp1100CheckDifficulty(commonAncestor, proposed *types.Header, ops *messOperands, getHeaderFunc func(common.Hash, uint64) *types.Header) error {
+	reference := commonAncestor.Difficulty
+	if reference == nil || reference.Sign() <= 0 {
+		return nil
+	}
+	lower := new(big.Int).Div(reference, big.NewInt(ecbp1100DifficultyBand))
+	upper := new(big.Int).Mul(reference, big.NewInt(ecbp1100DifficultyBand))
+	implausible := func(d *big.Int) bool {
+		return d == nil || d.Sign() <= 0 || d.Cmp(lower) < 0 || d.Cmp(upper) > 0
+	}
+	average := new(big.Int)
+	if proposed.Number.Cmp(commonAncestor.Number) > 0 {
+		average.Div(ops.proposedSubchainTD, new(big.Int).Sub(proposed.Number, commonAncestor.Number))
+	}
+	reject := func(block *types.Header) error {
+		return fmt.Errorf(`%w: ECBP1100-MESS 🔒 status=rejected reason=implausible-difficulty common.difficulty=%v block.difficulty=%v block.bno=%d block.hash=%s proposed.average=%v common.bno=%d common.hash=%s proposed.bno=%d proposed.hash=%s`,
+			errReorgFinalityMESS,
+			reference, block.Difficulty, block.Number.Uint64(), block.Hash().Hex(), average,
+			commonAncestor.Number.Uint64(), commonAncestor.Hash().Hex(),
+			proposed.Number.Uint64(), proposed.Hash().Hex(),
+		)
+	}
+	for h := proposed; h != nil && h.Number.Cmp(commonAncestor.Number) > 0; h = getHeaderFunc(h.ParentHash, h.Number.Uint64()-1) {
+		if implausible(h.Difficulty) {
+			return reject(h)
+		}
+	}
+	if implausible(average) {
+		return reject(proposed)
+	}
+	return nil
+}
+
+// ecbp1100Operands computes the operands of the ECBP1100 (MESS) comparison
+// against the given curve for a reorg from current to proposed, forking at
+// commonAncestor.
+func ecbp1100Operands(curve *messCurve, commonAncestor, current, proposed *types.Header, getTDFunc func(common.Hash, uint64) *big.Int) (*messOperands, error) {
+	if err := ecbp1100CheckAge(commonAncestor, current); err != nil {
+		return nil, err
+	}
+	// The proposed block must descend from the common ancestor, which also
+	// rules out a genesis proposal whose parent number would underflow.
+	if proposed.Number.Sign() <= 0 || proposed.Number.Cmp(commonAncestor.Number) <= 0 {
+		return nil, fmt.Errorf(`%w: ECBP1100-MESS 🔒 status=rejected reason=invalid-segment common.bno=%d common.hash=%s proposed.bno=%d proposed.hash=%s`,
+			errReorgFinalityMESS,
+			commonAncestor.Number.Uint64(), commonAncestor.Hash().Hex(),
+			proposed.Number.Uint64(), proposed.Hash().Hex(),
+		)
+	}
+	// Get the total difficulties of the proposed chain segment and the existing one.
+	commonAncestorTD := getTDFunc(commonAncestor.Hash(), commonAncestor.Number.Uint64())
+	proposedParentTD := getTDFunc(proposed.ParentHash, proposed.Number.Uint64()-1)
+	localTD := getTDFunc(current.Hash(), current.Number.Uint64())
+	if commonAncestorTD == nil || proposedParentTD == nil || localTD == nil {
+		return nil, fmt.Errorf("ECBP1100-MESS: missing total difficulty common.bno=%d current.bno=%d proposed.bno=%d",
+			commonAncestor.Number.Uint64(), current.Number.Uint64(), proposed.Number.Uint64())
+	}
+	proposedTD := new(big.Int).Add(proposed.Difficulty, proposedParentTD)
+
+	// if proposed_subchain_td * curve.denominator < curve.numerator(current.Time - commonAncestor.Time) * local_subchain_td.
+	ops := &messOperands{
+		age:                big.NewInt(int64(current.Time - commonAncestor.Time)),
+		localSubchainTD:    new(big.Int).Sub(localTD, commonAncestorTD),
+		proposedSubchainTD: new(big.Int).Sub(proposedTD, commonAncestorTD),
+	}
+	eq := curve.numerator(ops.age)
+	ops.want = eq.Mul(eq, ops.localSubchainTD)
+	ops.got = new(big.Int).Mul(ops.proposedSubchainTD, curve.denominator)
+	return ops, nil
+}
+
+// RequiredProposedTD returns the minimum proposed subchain TD that satisfies
+// the ECBP1100 (MESS) polynomial curve against the given local subchain TD,
+// when the common ancestor is ageSeconds older than the current head. It is
+// the inverse of the MESS check: want / CURVE_FUNCTION_DENOMINATOR, rounded
+// up so that got >= want. Under the favor-incumbent tie break, a proposal
+// must exceed the returned value. It returns nil for a nil local subchain TD,
+// as for a pruned or unknown header, or a negative one.
+func RequiredProposedTD(ageSeconds uint64, localSubchainTD *big.Int) *big.Int {
+	if localSubchainTD == nil || localSubchainTD.Sign() < 0 {
+		return nil
+	}
+	want := ecbp1100PolynomialV(new(big.Int).SetUint64(ageSeconds))
+	want.Mul(want, localSubchainTD)
+
+	// ceil(want / denominator) for the non-negative operands of a reorg.
+	want.Add(want, ecbp1100PolynomialVCurveFunctionDenominator)
+	want.Sub(want, big.NewInt(1))
+	return want.Div(want, ecbp1100PolynomialVCurveFunctionDenominator)
+}
+
 /*
 ecbp1100PolynomialV is a cubic function that looks a lot like Option 3's sin function,
 but adds the benefit that the calculation can be done with integers (instead of yucky floating points).
@@ -250,15 +1738,75 @@ func ecbp1100AGSinusoidalA(x float64) (antiGravity float64) {
 	return (ampl * math.Sin((x+phaseShift)/pDiv)) + ampl + 1
 }
 
+// ecbp1100SinusoidalVScale is the fixed-point scale used by ecbp1100SinusoidalV.
+var ecbp1100SinusoidalVScale = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// ecbp1100SinusoidalVPeriodDivisor is the period divisor of the sinusoidal curve.
+var ecbp1100SinusoidalVPeriodDivisor = big.NewInt(8000)
+
+/*
+ecbp1100SinusoidalV is the integer counterpart of ecbp1100AGSinusoidalA, returning
+the curve numerator over CURVE_FUNCTION_DENOMINATOR like ecbp1100PolynomialV.
+Using 15 sin((x+12000 π)/8000) + 15 + 1 = 1 + 15 (1 - cos(x/8000)), it computes
+
+	CURVE_FUNCTION_DENOMINATOR + height * (1 - cos(x/8000)) / 2
+
+with x capped at xcap, and cos evaluated by its Taylor series in fixed-point
+arithmetic at scale 10**18. Only truncating integer operations are used, so the
+result is the same on every platform.
+*/
+func ecbp1100SinusoidalV(x *big.Int) *big.Int {
+	xA := new(big.Int).Set(x)
+	if xA.Cmp(ecbp1100PolynomialVXCap) > 0 {
+		xA.Set(ecbp1100PolynomialVXCap)
+	}
+	scale := ecbp1100SinusoidalVScale
+
+	// theta = x / 8000, scaled
+	theta := new(big.Int).Mul(xA, scale)
+	theta.Quo(theta, ecbp1100SinusoidalVPeriodDivisor)
+	theta2 := new(big.Int).Mul(theta, theta)
+	theta2.Quo(theta2, scale)
+
+	// cos(theta) = sum_k (-1)**k * theta**(2k) / (2k)!
+	cos := new(big.Int).Set(scale)
+	term := new(big.Int).Set(scale)
+	for k := int64(1); ; k++ {
+		term.Mul(term, theta2)
+		term.Quo(term, scale)
+		term.Quo(term, big.NewInt((2*k-1)*(2*k)))
+		if term.Sign() == 0 {
+			break
+		}
+		if k%2 == 1 {
+			cos.Sub(cos, term)
+		} else {
+			cos.Add(cos, term)
+		}
+	}
+	// height * (1 - cos(theta)) / 2
+	out := new(big.Int).Sub(scale, cos)
+	out.Mul(out, ecbp1100PolynomialVHeight)
+	out.Quo(out, new(big.Int).Mul(scale, big2))
+
+	return out.Add(out, ecbp1100PolynomialVCurveFunctionDenominator)
+}
+
 /*
 ecbp1100AGExpB is an exponential function with x as a base (and rationalized exponent).
 
 OPTION 2: Slightly slower takeoff, steeper eventual ascent
 g(x)=x^(x*0.00002)
+
+The result is clamped to [1, ecbp1100AGCeiling], which also keeps it monotonic:
+the curve dips below 1 for 0 < x < 1, and non-positive or NaN x would make it
+undefined.
 */
-//nolint:deadcode,unused
 func ecbp1100AGExpB(x float64) (antiGravity float64) {
-	return math.Pow(x, x*0.00002)
+	if !(x > 1) {
+		return 1
+	}
+	return math.Min(math.Pow(x, x*0.00002), ecbp1100AGCeiling)
 }
 
 /*
@@ -272,8 +1820,17 @@ This was (one of?) Vitalik's "original" specs:
 
 OPTION 1 (Original ESS)
 f(x)=1.0001^(x)
+
+The result is clamped to [1, ecbp1100AGCeiling]; it would otherwise overflow
+to +Inf for large x.
 */
-//nolint:unused
 func ecbp1100AGExpA(x float64) (antiGravity float64) {
-	return math.Pow(1.0001, x)
+	if !(x > 0) {
+		return 1
+	}
+	return math.Min(math.Pow(1.0001, x), ecbp1100AGCeiling)
 }
+
+// ecbp1100AGCeiling caps the exponential anti-gravity curves at the ceiling of
+// the sinusoidal one, 2 * ampl + 1.
+const ecbp1100AGCeiling = 31
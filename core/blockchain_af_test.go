@@ -1,12 +1,19 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image/color"
+	"io"
 	"log"
 	"math"
 	"math/big"
 	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,7 +22,12 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	gethlog "github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/coregeth"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
 	"github.com/ethereum/go-ethereum/trie"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
@@ -246,6 +258,1261 @@ func TestAFKnownBlock(t *testing.T) {
 	}
 }
 
+// TestEnableArtificialFinalityConcurrentNoDisable hammers the AF toggle from
+// several goroutines and checks that, once enabled under no-disable, the
+// feature is never observed disabled.
+func TestEnableArtificialFinalityConcurrentNoDisable(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	chain.ArtificialFinalityNoDisable(1)
+	chain.EnableArtificialFinality(true)
+
+	var (
+		wg   sync.WaitGroup
+		stop = make(chan struct{})
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				chain.EnableArtificialFinality((i+j)%2 == 0)
+			}
+		}(i)
+	}
+	var (
+		violated    = make(chan struct{}, 1)
+		checkerDone = make(chan struct{})
+	)
+	go func() {
+		defer close(checkerDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if !chain.IsArtificialFinalityEnabled() {
+				select {
+				case violated <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	<-checkerDone
+
+	select {
+	case <-violated:
+		t.Fatal("artificial finality was disabled despite no-disable override")
+	default:
+	}
+	if !chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality disabled after concurrent toggling")
+	}
+}
+
+func TestClearArtificialFinalityNoDisable(t *testing.T) {
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, params.DefaultMessNetGenesisBlock(), nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	chain.ArtificialFinalityNoDisable(1)
+	chain.EnableArtificialFinality(true)
+	chain.EnableArtificialFinality(false)
+	if !chain.IsArtificialFinalityEnabled() {
+		t.Fatal("disabled despite no-disable override")
+	}
+	chain.ClearArtificialFinalityNoDisable()
+	if cfg := chain.ArtificialFinalityConfig(); cfg.NoDisable || !cfg.Enabled {
+		t.Fatalf("unexpected status after clearing: enabled=%v nodisable=%v", cfg.Enabled, cfg.NoDisable)
+	}
+	chain.EnableArtificialFinality(false)
+	if chain.IsArtificialFinalityEnabled() {
+		t.Fatal("still enabled after clearing the override")
+	}
+	chain.EnableArtificialFinality(true)
+	if !chain.IsArtificialFinalityEnabled() {
+		t.Fatal("failed to re-enable after clearing the override")
+	}
+	// Clearing without an override is a noop.
+	chain.ClearArtificialFinalityNoDisable()
+}
+
+// TestEnableArtificialFinalityNoHead tests that toggling AF on a chain without
+// a head header records the status without dereferencing the missing head.
+func TestEnableArtificialFinalityNoHead(t *testing.T) {
+	bc := &BlockChain{chainConfig: params.MessNetConfig, hc: new(HeaderChain)}
+	bc.hc.currentHeader.Store((*types.Header)(nil))
+
+	bc.EnableArtificialFinality(true)
+	if !bc.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality not enabled")
+	}
+	bc.EnableArtificialFinality(false)
+	if bc.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality not disabled")
+	}
+}
+
+// newAFTestChain creates a chain with artificial finality enabled holding 1000
+// easy blocks, and returns it with those and a 25 block hard chain forking off
+// at block 975, which MESS rejects. The hard chain is not imported.
+func newAFTestChain(t *testing.T) (*BlockChain, ethdb.Database, *genesisT.Genesis, []*types.Block, []*types.Block) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.EnableArtificialFinality(true)
+
+	easy, _ := GenerateChain(genesis.Config, genesisB, engine, db, 1000, func(i int, b *BlockGen) {
+		b.OffsetTime(0)
+	})
+	hard, _ := GenerateChain(genesis.Config, easy[974], engine, db, 25, func(i int, b *BlockGen) {
+		b.OffsetTime(-2)
+	})
+	if _, err := chain.InsertChain(easy); err != nil {
+		t.Fatal(err)
+	}
+	return chain, db, genesis, easy, hard
+}
+
+// TestWhitelistReorgTarget tests that a whitelisted block lets an otherwise
+// MESS-rejected chain become canonical, and that the whitelist is persisted.
+func TestWhitelistReorgTarget(t *testing.T) {
+	chain, db, genesis, _, hard := newAFTestChain(t)
+
+	chain.WhitelistReorgTarget(hard[0].Hash())
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	if chain.CurrentBlock().Hash() != hard[len(hard)-1].Hash() {
+		t.Fatal("whitelisted chain did not become canonical")
+	}
+	chain.Stop()
+
+	// Reopen the chain and check the whitelist survived.
+	chain, err := NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	if wl := chain.ReorgWhitelist(); len(wl) != 1 || wl[0] != hard[0].Hash() {
+		t.Fatalf("whitelist not restored: %v", wl)
+	}
+	chain.ClearReorgWhitelist()
+	if wl := chain.ReorgWhitelist(); len(wl) != 0 {
+		t.Fatalf("whitelist not cleared: %v", wl)
+	}
+}
+
+type rejectingScorer struct {
+	calls int
+}
+
+func (s *rejectingScorer) ScoreReorg(commonAncestor, current, proposed *types.Header) error {
+	s.calls++
+	return fmt.Errorf("%w: rejected by test scorer", errReorgFinality)
+}
+
+// TestAddConsensusScorer tests that a registered scorer is consulted for reorgs
+// and can disallow one that MESS would allow.
+func TestAddConsensusScorer(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.EnableArtificialFinality(true)
+
+	scorer := new(rejectingScorer)
+	chain.AddConsensusScorer(scorer)
+
+	easy, _ := GenerateChain(genesis.Config, genesisB, engine, db, 20, func(i int, b *BlockGen) {
+		b.OffsetTime(0)
+	})
+	hard, _ := GenerateChain(genesis.Config, easy[16], engine, db, 4, func(i int, b *BlockGen) {
+		b.OffsetTime(-2)
+	})
+	if _, err := chain.InsertChain(easy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	if scorer.calls == 0 {
+		t.Fatal("registered scorer was not consulted")
+	}
+	if chain.CurrentBlock().Hash() != easy[len(easy)-1].Hash() {
+		t.Fatal("reorg allowed despite rejecting scorer")
+	}
+}
+
+type messRejectingScorer struct{}
+
+func (messRejectingScorer) ScoreReorg(commonAncestor, current, proposed *types.Header) error {
+	return fmt.Errorf("%w: rejected by test mess scorer", errReorgFinalityMESS)
+}
+
+// TestConsensusScorersAllRejections tests that the rejections of all scorers
+// are reported, in evaluation order.
+func TestConsensusScorersAllRejections(t *testing.T) {
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, params.DefaultMessNetGenesisBlock(), nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	commonAncestor := &types.Header{Number: big.NewInt(10)}
+	current := &types.Header{Number: big.NewInt(20), ParentHash: common.Hash{0x01}}
+	proposed := &types.Header{Number: big.NewInt(21), ParentHash: common.Hash{0x02}}
+
+	scorer := new(rejectingScorer)
+	chain.consensusScorers = []ConsensusScorer{messRejectingScorer{}, scorer}
+	mechanism, err := chain.scoreArtificialFinality(commonAncestor, current, proposed)
+	if scorer.calls != 1 {
+		t.Fatalf("second scorer called %d times, want 1", scorer.calls)
+	}
+	if !errors.Is(err, errReorgFinalityMESS) || !strings.Contains(err.Error(), "test mess scorer") || !strings.Contains(err.Error(), "rejected by test scorer") {
+		t.Fatalf("expected both rejections, got %v", err)
+	}
+	if strings.Index(err.Error(), "test mess scorer") > strings.Index(err.Error(), "rejected by test scorer") {
+		t.Errorf("rejections not reported in evaluation order: %v", err)
+	}
+	if mechanism != "core.messRejectingScorer" {
+		t.Errorf("mechanism %q, want the first rejecting scorer", mechanism)
+	}
+}
+
+// TestMESSDecision tests that MESSDecision reports the MESS evaluation of a
+// rejected side chain without reorganizing.
+func TestMESSDecision(t *testing.T) {
+	chain, _, _, easy, hard := newAFTestChain(t)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	if chain.CurrentBlock().Hash() != easy[len(easy)-1].Hash() {
+		t.Fatal("expected MESS to reject the reorg")
+	}
+	// An ancestor of the head is not a reorg.
+	d, err := chain.MESSDecision(easy[500].Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Reorg || d.Rejected != nil {
+		t.Errorf("canonical ancestor: reorg=%v rejected=%v", d.Reorg, d.Rejected)
+	}
+	d, err = chain.MESSDecision(hard[len(hard)-1].Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Reorg || !errors.Is(d.Rejected, errReorgFinality) {
+		t.Errorf("side chain: reorg=%v rejected=%v", d.Reorg, d.Rejected)
+	}
+	if d.CommonAncestor.Hash() != easy[974].Hash() {
+		t.Errorf("common ancestor %d, want %d", d.CommonAncestor.Number, easy[974].Number())
+	}
+	if d.ProposedSubchainTD.Cmp(d.LocalSubchainTD) <= 0 {
+		t.Errorf("expected heavier proposed subchain: local %v proposed %v", d.LocalSubchainTD, d.ProposedSubchainTD)
+	}
+	if _, err := chain.MESSDecision(common.Hash{0x01}); err == nil {
+		t.Error("expected error for unknown block")
+	}
+}
+
+// TestMESSCurveComparison tests that MESSCurveComparison evaluates a rejected
+// side chain under each candidate curve, the consensus one agreeing with MESS.
+func TestMESSCurveComparison(t *testing.T) {
+	chain, _, _, easy, hard := newAFTestChain(t)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	evals, err := chain.MESSCurveComparison(hard[len(hard)-1].Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evals) != len(messComparisonCurves) {
+		t.Fatalf("got %d curve evaluations, want %d", len(evals), len(messComparisonCurves))
+	}
+	if evals[0].Curve != "polynomialV" || evals[0].Accepted || evals[0].Margin >= 1 {
+		t.Errorf("unexpected polynomialV evaluation: %+v", evals[0])
+	}
+	if evals[1].Curve != "sinusoidalV" || evals[1].Margin <= 0 {
+		t.Errorf("unexpected sinusoidalV evaluation: %+v", evals[1])
+	}
+	if _, err := chain.MESSCurveComparison(easy[500].Hash()); err == nil {
+		t.Error("expected error comparing curves for a canonical block")
+	}
+}
+
+// TestReorgRejectionStatsRejectedReorg tests that a reorg rejected by MESS on
+// import is recorded in the rejection stats.
+func TestReorgRejectionStatsRejectedReorg(t *testing.T) {
+	chain, _, _, _, hard := newAFTestChain(t)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	if stats := chain.ReorgRejectionStats(); stats.Count == 0 || stats.MaxSpan != 25 {
+		t.Errorf("unexpected rejection stats %+v", stats)
+	}
+}
+
+// TestTraceAFDecision tests that the trace of a rejected reorg lists both
+// segments from the common ancestor and the rejected operands, and that a
+// canonical block is traced as no reorg.
+func TestTraceAFDecision(t *testing.T) {
+	chain, _, _, easy, hard := newAFTestChain(t)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := chain.TraceAFDecision(easy[len(easy)-1].Hash(), hard[len(hard)-1].Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace AFTrace
+	if err := json.Unmarshal(blob, &trace); err != nil {
+		t.Fatal(err)
+	}
+	if !trace.Reorg || trace.Accepted || trace.Reason == "" || trace.CommonAncestor.Hash != easy[974].Hash() {
+		t.Errorf("unexpected trace decision: reorg=%v accepted=%v reason=%q ancestor=%x", trace.Reorg, trace.Accepted, trace.Reason, trace.CommonAncestor.Hash)
+	}
+	if len(trace.Current) != 25 || len(trace.Proposed) != 25 {
+		t.Fatalf("got %d current and %d proposed blocks, want 25 each", len(trace.Current), len(trace.Proposed))
+	}
+	if trace.Proposed[0].Hash != hard[0].Hash() || trace.Current[24].Hash != easy[len(easy)-1].Hash() {
+		t.Error("trace segments not in ascending order from the common ancestor")
+	}
+	if (*big.Int)(trace.Got).Cmp((*big.Int)(trace.Want)) >= 0 {
+		t.Errorf("rejected reorg traced with got %v >= want %v", trace.Got, trace.Want)
+	}
+	blob, err = chain.TraceAFDecision(easy[len(easy)-1].Hash(), easy[500].Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace = AFTrace{}
+	if err := json.Unmarshal(blob, &trace); err != nil {
+		t.Fatal(err)
+	}
+	if trace.Reorg || !trace.Accepted || len(trace.Current) != 0 || len(trace.Proposed) != 0 {
+		t.Errorf("unexpected trace of a canonical block: %s", blob)
+	}
+	if _, err := chain.TraceAFDecision(easy[len(easy)-1].Hash(), common.Hash{0x01}); err == nil {
+		t.Error("expected error tracing an unknown block")
+	}
+}
+
+func TestBlockChainCommonAncestor(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	canon, _ := GenerateChain(genesis.Config, genesisB, engine, db, 20, nil)
+	side, _ := GenerateChain(genesis.Config, canon[9], engine, db, 5, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := chain.InsertChain(canon); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(side); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		a, b *types.Header
+		want common.Hash
+	}{
+		{"side chain", canon[19].Header(), side[4].Header(), canon[9].Hash()},
+		{"reversed", side[4].Header(), canon[19].Header(), canon[9].Hash()},
+		{"ancestor", canon[19].Header(), canon[4].Header(), canon[4].Hash()},
+		{"same", canon[7].Header(), canon[7].Header(), canon[7].Hash()},
+	} {
+		ancestor, err := chain.CommonAncestor(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ancestor.Hash() != tt.want {
+			t.Errorf("%s: common ancestor %d, want %x", tt.name, ancestor.Number, tt.want)
+		}
+	}
+	// A header whose ancestry is unknown has no common ancestor.
+	orphan := types.CopyHeader(side[4].Header())
+	orphan.ParentHash = common.Hash{0x02}
+	if _, err := chain.CommonAncestor(canon[19].Header(), orphan); err == nil {
+		t.Error("expected error for a header with unknown ancestry")
+	}
+}
+
+// TestArtificialFinalityNoCommonAncestor tests that a reorg whose fork point is
+// missing from the database, as after pruning, is rejected instead of failing.
+func TestArtificialFinalityNoCommonAncestor(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	easy, _ := GenerateChain(genesis.Config, genesisB, engine, db, 100, nil)
+	hard, _ := GenerateChain(genesis.Config, easy[49], engine, db, 51, func(i int, b *BlockGen) {
+		b.OffsetTime(-2)
+	})
+	if _, err := chain.InsertChain(easy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	chain.Stop()
+
+	// Drop the fork point and reopen the chain, so no header cache holds it.
+	rawdb.DeleteHeader(db, easy[49].Hash(), easy[49].NumberU64())
+	chain, err = NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.EnableArtificialFinality(true)
+
+	current, proposed := easy[len(easy)-1].Header(), hard[len(hard)-1].Header()
+	if _, err := chain.CommonAncestor(current, proposed); err == nil {
+		t.Fatal("expected no common ancestor after pruning the fork point")
+	}
+	// The fork choice rejects the reorg instead of failing with the lookup.
+	reorg, err := chain.forker.ReorgNeeded(current, proposed)
+	if err != nil {
+		t.Fatalf("missing common ancestor reported as an error: %v", err)
+	}
+	if reorg {
+		t.Error("reorg without a common ancestor allowed")
+	}
+	if err := chain.evaluateArtificialFinality(nil, current, proposed); !errors.Is(err, errNoCommonAncestor) || !errors.Is(err, errReorgFinality) {
+		t.Errorf("got %v, want %v", err, errNoCommonAncestor)
+	}
+}
+
+// benchmarkCommonAncestor measures finding the common ancestors of a burst of
+// competing blocks, all extending the same side chain, with the current head.
+func benchmarkCommonAncestor(b *testing.B, cached bool) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer chain.Stop()
+
+	easy, _ := GenerateChain(genesis.Config, genesisB, engine, db, 1000, nil)
+	side, _ := GenerateChain(genesis.Config, easy[499], engine, db, 499, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := chain.InsertChain(easy); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := chain.InsertChain(side); err != nil {
+		b.Fatal(err)
+	}
+	var competing []*types.Header
+	for i := 0; i < 16; i++ {
+		blocks, _ := GenerateChain(genesis.Config, side[len(side)-1], engine, db, 1, func(_ int, b *BlockGen) {
+			b.SetCoinbase(common.Address{0x02, byte(i)})
+		})
+		rawdb.WriteHeader(db, blocks[0].Header())
+		competing = append(competing, blocks[0].Header())
+	}
+	f := NewForkChoice(chain, nil)
+	current := chain.CurrentHeader()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, proposed := range competing {
+			if cached {
+				_, err = f.cachedCommonAncestor(current, proposed)
+			} else {
+				_, err = f.CommonAncestor(current, proposed)
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCommonAncestor(b *testing.B)       { benchmarkCommonAncestor(b, false) }
+func BenchmarkCommonAncestorCached(b *testing.B) { benchmarkCommonAncestor(b, true) }
+
+// newReorgBenchChain creates a database holding a chain of 1000 blocks and the
+// headers of a heavier competing chain forking off at block 500, and returns it
+// with the head of the competing chain.
+func newReorgBenchChain(tb testing.TB) (ethdb.Database, *genesisT.Genesis, *types.Header) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer chain.Stop()
+
+	easy, _ := GenerateChain(genesis.Config, genesisB, engine, db, 1000, nil)
+	side, _ := GenerateChain(genesis.Config, easy[499], engine, db, 501, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+		b.OffsetTime(-9)
+	})
+	if _, err := chain.InsertChain(easy); err != nil {
+		tb.Fatal(err)
+	}
+	// Store the competing chain without importing it, so the head stays put.
+	td := chain.GetTd(easy[499].Hash(), easy[499].NumberU64())
+	for _, block := range side {
+		td = new(big.Int).Add(td, block.Difficulty())
+		rawdb.WriteHeader(db, block.Header())
+		rawdb.WriteTd(db, block.Hash(), block.NumberU64(), td)
+	}
+	return db, genesis, side[len(side)-1].Header()
+}
+
+func TestWarmReorgCaches(t *testing.T) {
+	db, genesis, proposed := newReorgBenchChain(t)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	current := chain.CurrentHeader()
+	chain.WarmReorgCaches(current.Hash(), proposed.Hash())
+	for _, header := range []*types.Header{current, proposed, chain.GetHeaderByNumber(500)} {
+		if !chain.hc.tdCache.Contains(header.Hash()) {
+			t.Errorf("total difficulty of block %d not cached", header.Number)
+		}
+	}
+}
+
+// benchmarkReorgDecision measures the latency of the fork choice for a reorg
+// against a freshly opened chain, optionally warming the caches beforehand.
+func benchmarkReorgDecision(b *testing.B, warm bool) {
+	db, genesis, proposed := newReorgBenchChain(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		chain, err := NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		chain.EnableArtificialFinality(true)
+		current := chain.CurrentHeader()
+		if warm {
+			chain.WarmReorgCaches(current.Hash(), proposed.Hash())
+		}
+		f := NewForkChoice(chain, nil)
+		b.StartTimer()
+
+		if _, err := f.ReorgNeeded(current, proposed); err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		chain.Stop()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkReorgDecision(b *testing.B)       { benchmarkReorgDecision(b, false) }
+func BenchmarkReorgDecisionWarmed(b *testing.B) { benchmarkReorgDecision(b, true) }
+
+func TestSetArtificialFinalityLogLevel(t *testing.T) {
+	defer gethlog.Root().SetHandler(gethlog.Root().GetHandler())
+
+	var buf bytes.Buffer
+	glog := gethlog.NewGlogHandler(gethlog.StreamHandler(&buf, gethlog.LogfmtFormat()))
+	glog.Verbosity(gethlog.LvlInfo)
+	gethlog.Root().SetHandler(glog)
+
+	bc := &BlockChain{}
+	bc.afLogLevel.Store(-1)
+	bc.afLogger = gethlog.New()
+	bc.afLogger.SetHandler(bc.afLogHandler())
+
+	bc.afLogger.Debug("af debug")
+	if buf.Len() != 0 {
+		t.Fatalf("debug log emitted at global info verbosity: %s", buf.String())
+	}
+	bc.SetArtificialFinalityLogLevel(gethlog.LvlDebug)
+	bc.afLogger.Debug("af debug")
+	bc.afLogger.Trace("af trace")
+	if !strings.Contains(buf.String(), "af debug") || strings.Contains(buf.String(), "af trace") {
+		t.Fatalf("unexpected output at af debug verbosity: %s", buf.String())
+	}
+	buf.Reset()
+	bc.SetArtificialFinalityLogLevel(gethlog.LvlError)
+	bc.afLogger.Warn("af warn")
+	if buf.Len() != 0 {
+		t.Fatalf("warn log emitted at af error verbosity: %s", buf.String())
+	}
+}
+
+func TestArtificialFinalityRejectionLogLimit(t *testing.T) {
+	defer gethlog.Root().SetHandler(gethlog.Root().GetHandler())
+
+	var buf bytes.Buffer
+	gethlog.Root().SetHandler(gethlog.StreamHandler(&buf, gethlog.LogfmtFormat()))
+
+	bc := &BlockChain{afLogLimiter: newAFLogLimiter(time.Minute)}
+	bc.afLogLevel.Store(-1)
+	bc.afLogger = gethlog.New()
+	bc.afLogger.SetHandler(bc.afLogHandler())
+
+	a := &types.Header{Number: big.NewInt(1)}
+	b := &types.Header{Number: big.NewInt(2)}
+	for i := 0; i < 100; i++ {
+		bc.afLimitedLogger(a).Warn("af rejected")
+		bc.afLimitedLogger(a).Info("af info")
+	}
+	bc.afLimitedLogger(b).Warn("af rejected")
+	if n := strings.Count(buf.String(), "af rejected"); n != 2 {
+		t.Errorf("got %d rejection warnings for a burst on 2 ancestors, want 2:\n%s", n, buf.String())
+	}
+	if n := strings.Count(buf.String(), "af info"); n != 100 {
+		t.Errorf("got %d info lines, want all 100 of them", n)
+	}
+	// Suppressed warnings are summarized by the next one let through.
+	l := newAFLogLimiter(time.Minute)
+	start := time.Now()
+	if ok, _ := l.allow("msg", a.Hash(), start); !ok {
+		t.Fatal("first warning suppressed")
+	}
+	for i := 1; i <= 3; i++ {
+		if ok, _ := l.allow("msg", a.Hash(), start.Add(time.Duration(i)*time.Second)); ok {
+			t.Fatalf("warning %d within the interval allowed", i)
+		}
+	}
+	if ok, _ := l.allow("other msg", a.Hash(), start.Add(time.Second)); !ok {
+		t.Error("warning with a different message suppressed")
+	}
+	if ok, suppressed := l.allow("msg", a.Hash(), start.Add(time.Minute)); !ok || suppressed != 3 {
+		t.Errorf("after the interval: allowed=%v suppressed=%d, want true 3", ok, suppressed)
+	}
+	bc.SetArtificialFinalityRejectionLogInterval(0)
+	buf.Reset()
+	for i := 0; i < 10; i++ {
+		bc.afLimitedLogger(a).Warn("af rejected")
+	}
+	if n := strings.Count(buf.String(), "af rejected"); n != 10 {
+		t.Errorf("got %d warnings without a rate limit, want 10", n)
+	}
+}
+
+func TestArtificialFinalityHeadExtension(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.EnableArtificialFinality(true)
+
+	scorer := new(rejectingScorer)
+	chain.AddConsensusScorer(scorer)
+
+	blocks, _ := GenerateChain(genesis.Config, genesisB, engine, db, 30, nil)
+	for _, block := range blocks {
+		if _, err := chain.InsertChain([]*types.Block{block}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if scorer.calls != 0 {
+		t.Fatalf("artificial finality evaluated %d times for head extensions", scorer.calls)
+	}
+	if chain.CurrentBlock().Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatal("head extension rejected")
+	}
+}
+
+// TestArtificialFinalityLowerTDSideChain tests that side chains with less total
+// difficulty than the head don't trigger an artificial finality evaluation.
+func TestArtificialFinalityLowerTDSideChain(t *testing.T) {
+	defer gethlog.Root().SetHandler(gethlog.Root().GetHandler())
+
+	var buf bytes.Buffer
+	gethlog.Root().SetHandler(gethlog.LvlFilterHandler(gethlog.LvlTrace, gethlog.StreamHandler(&buf, gethlog.LogfmtFormat())))
+
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, trie.NewDatabase(db, nil), genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.EnableArtificialFinality(true)
+
+	scorer := new(rejectingScorer)
+	chain.AddConsensusScorer(scorer)
+
+	blocks, _ := GenerateChain(genesis.Config, genesisB, engine, db, 30, nil)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	side, _ := GenerateChain(genesis.Config, blocks[9], engine, db, 5, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	buf.Reset()
+	if _, err := chain.InsertChain(side); err != nil {
+		t.Fatal(err)
+	}
+	if chain.CurrentBlock().Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatal("lower total difficulty side chain became canonical")
+	}
+	// The fork choice bails out before artificial finality for lower total
+	// difficulty, so neither the scorers nor MESS see the side chain.
+	if scorer.calls != 0 {
+		t.Fatalf("artificial finality evaluated %d times for lower total difficulty side chain", scorer.calls)
+	}
+	if strings.Contains(buf.String(), "ECBP1100") {
+		t.Fatalf("MESS logged for lower total difficulty side chain: %s", buf.String())
+	}
+}
+
+func TestSetArtificialFinalityPersisted(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+
+	chain, err := NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality enabled by default")
+	}
+	chain.SetArtificialFinality(true)
+	chain.Stop()
+
+	// Reopen the chain, simulating a restart.
+	chain, err = NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.RestoreArtificialFinality()
+	if !chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality status not restored")
+	}
+	chain.SetArtificialFinality(false)
+	chain.Stop()
+
+	chain, err = NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.RestoreArtificialFinality()
+	if chain.IsArtificialFinalityEnabled() {
+		t.Fatal("disabled artificial finality status not restored")
+	}
+	// Automatic toggles still apply, but are not persisted.
+	chain.EnableArtificialFinality(true, "reason", "synced")
+	if !chain.IsArtificialFinalityEnabled() {
+		t.Fatal("automatic toggle ignored after restore")
+	}
+	chain.Stop()
+
+	chain, err = NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.RestoreArtificialFinality()
+	if chain.IsArtificialFinalityEnabled() {
+		t.Fatal("automatic toggle persisted")
+	}
+}
+
+func TestArtificialFinalityQuarantine(t *testing.T) {
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, params.DefaultMessNetGenesisBlock(), nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	// Only consult the test scorer, as the headers have no total difficulty.
+	scorer := new(rejectingScorer)
+	chain.consensusScorers = []ConsensusScorer{scorer}
+
+	commonAncestor := &types.Header{Number: big.NewInt(10)}
+	current := &types.Header{Number: big.NewInt(20), ParentHash: common.Hash{0x01}}
+	proposed := &types.Header{Number: big.NewInt(21), ParentHash: common.Hash{0x02}}
+	child := &types.Header{Number: big.NewInt(22), ParentHash: proposed.Hash()}
+
+	// The default mode evaluates re-offered chains again.
+	for i := 0; i < 2; i++ {
+		if err := chain.evaluateArtificialFinality(commonAncestor, current, proposed); !errors.Is(err, errReorgFinality) {
+			t.Fatalf("expected rejection, got %v", err)
+		}
+	}
+	if scorer.calls != 2 {
+		t.Fatalf("scorer called %d times, want 2", scorer.calls)
+	}
+	// In quarantine mode, the rejected head and chains on top of it are dropped.
+	chain.SetArtificialFinalityRejectionMode(AFRejectionQuarantine)
+	for _, header := range []*types.Header{proposed, proposed, child} {
+		if err := chain.evaluateArtificialFinality(commonAncestor, current, header); !errors.Is(err, errReorgFinality) {
+			t.Fatalf("expected rejection, got %v", err)
+		}
+	}
+	if scorer.calls != 3 {
+		t.Fatalf("scorer called %d times, want 3", scorer.calls)
+	}
+	// Once the cooldown has passed, the chain is evaluated again.
+	chain.afQuarantine.cooldown = 0
+	if err := chain.evaluateArtificialFinality(commonAncestor, current, child); errors.Is(err, errReorgFinalityQuarantined) {
+		t.Fatalf("quarantine did not expire: %v", err)
+	}
+	if scorer.calls != 4 {
+		t.Fatalf("scorer called %d times, want 4", scorer.calls)
+	}
+}
+
+func TestLastAcceptedReorg(t *testing.T) {
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, params.DefaultMessNetGenesisBlock(), nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	commonAncestor := &types.Header{Number: big.NewInt(10)}
+	current := &types.Header{Number: big.NewInt(20), ParentHash: common.Hash{0x01}}
+	proposed := &types.Header{Number: big.NewInt(21), ParentHash: common.Hash{0x02}}
+
+	if _, ok := chain.LastAcceptedReorg(); ok {
+		t.Fatal("unexpected accepted reorg on a fresh chain")
+	}
+	// Rejected reorgs are not recorded.
+	chain.consensusScorers = []ConsensusScorer{new(rejectingScorer)}
+	if err := chain.evaluateArtificialFinality(commonAncestor, current, proposed); err == nil {
+		t.Fatal("expected rejection")
+	}
+	if _, ok := chain.LastAcceptedReorg(); ok {
+		t.Fatal("rejected reorg recorded as accepted")
+	}
+	chain.consensusScorers = nil
+	if err := chain.evaluateArtificialFinality(commonAncestor, current, proposed); err != nil {
+		t.Fatal(err)
+	}
+	d, ok := chain.LastAcceptedReorg()
+	if !ok {
+		t.Fatal("accepted reorg not recorded")
+	}
+	if d.Proposed.Hash() != proposed.Hash() || d.Current.Hash() != current.Hash() || d.CommonAncestor.Hash() != commonAncestor.Hash() {
+		t.Errorf("recorded reorg does not match the evaluated one")
+	}
+}
+
+func TestArtificialFinalityObserver(t *testing.T) {
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, params.DefaultMessNetGenesisBlock(), nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	commonAncestor := &types.Header{Number: big.NewInt(10)}
+	current := &types.Header{Number: big.NewInt(20), ParentHash: common.Hash{0x01}}
+	proposed := &types.Header{Number: big.NewInt(21), ParentHash: common.Hash{0x02}}
+
+	// Decisions without an observer are not queued.
+	chain.consensusScorers = nil
+	chain.evaluateArtificialFinality(commonAncestor, current, proposed)
+
+	decisions := make(chan AFDecision, 2)
+	chain.SetArtificialFinalityObserver(func(d AFDecision) {
+		// The observer may call back into the chain.
+		chain.LastAcceptedReorg()
+		decisions <- d
+	})
+	chain.consensusScorers = []ConsensusScorer{new(rejectingScorer)}
+	chain.evaluateArtificialFinality(commonAncestor, current, proposed)
+	chain.consensusScorers = nil
+	chain.evaluateArtificialFinality(commonAncestor, current, proposed)
+
+	for i, want := range []struct {
+		mechanism string
+		rejected  bool
+	}{
+		{"*core.rejectingScorer", true},
+		{AFMechanismConsensus, false},
+	} {
+		select {
+		case d := <-decisions:
+			if d.Mechanism != want.mechanism || (d.Err != nil) != want.rejected {
+				t.Errorf("decision %d: mechanism %q err %v, want %q rejected=%v", i, d.Mechanism, d.Err, want.mechanism, want.rejected)
+			}
+			if d.Proposed.Hash() != proposed.Hash() {
+				t.Errorf("decision %d: unexpected proposed block", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("decision %d not observed", i)
+		}
+	}
+	select {
+	case d := <-decisions:
+		t.Fatalf("unexpected decision %+v", d)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestArtificialFinalityObserverFull tests that decisions are dropped rather
+// than blocking the evaluation once a stalled observer's queue is full.
+func TestArtificialFinalityObserverFull(t *testing.T) {
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, params.DefaultMessNetGenesisBlock(), nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.consensusScorers = nil
+
+	release := make(chan struct{})
+	defer close(release)
+	chain.SetArtificialFinalityObserver(func(AFDecision) { <-release })
+	commonAncestor := &types.Header{Number: big.NewInt(10)}
+	current := &types.Header{Number: big.NewInt(20), ParentHash: common.Hash{0x01}}
+	proposed := &types.Header{Number: big.NewInt(21), ParentHash: common.Hash{0x02}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*afObserverQueue; i++ {
+			chain.evaluateArtificialFinality(commonAncestor, current, proposed)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("evaluation blocked on a stalled observer")
+	}
+	// One decision is held by the observer, and at most a queue full pending.
+	if pending := len(chain.afDecisions); pending > afObserverQueue {
+		t.Fatalf("%d decisions pending, want at most %d", pending, afObserverQueue)
+	}
+}
+
+func TestSetArtificialFinalityConfirmed(t *testing.T) {
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, params.DefaultMessNetGenesisBlock(), nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	// Without confirmation, a single call disables.
+	chain.EnableArtificialFinality(true)
+	if challenge, err := chain.SetArtificialFinalityConfirmed(false, ""); err != nil || challenge != "" {
+		t.Fatalf("unexpected challenge %q, err %v", challenge, err)
+	}
+	if chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality not disabled")
+	}
+	chain.SetArtificialFinalityDisableConfirmation(true)
+	if _, err := chain.SetArtificialFinalityConfirmed(true, ""); err != nil || !chain.IsArtificialFinalityEnabled() {
+		t.Fatalf("enabling needs no confirmation: enabled=%v err=%v", chain.IsArtificialFinalityEnabled(), err)
+	}
+	challenge, err := chain.SetArtificialFinalityConfirmed(false, "")
+	if err != nil || challenge == "" {
+		t.Fatalf("expected a challenge, got %q, err %v", challenge, err)
+	}
+	if !chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality disabled without confirmation")
+	}
+	if _, err := chain.SetArtificialFinalityConfirmed(false, "0xbad"); !errors.Is(err, errAFDisableChallenge) {
+		t.Fatalf("expected invalid challenge error, got %v", err)
+	}
+	if _, err := chain.SetArtificialFinalityConfirmed(false, challenge); err != nil {
+		t.Fatal(err)
+	}
+	if chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality not disabled after confirmation")
+	}
+	// The confirmed disable is persisted as the operator choice.
+	if enabled := rawdb.ReadArtificialFinalityEnabled(chain.db); enabled == nil || *enabled {
+		t.Fatalf("confirmed disable not persisted: %v", enabled)
+	}
+	// A challenge can only be used once, and expires.
+	if _, err := chain.SetArtificialFinalityConfirmed(false, challenge); !errors.Is(err, errAFDisableChallenge) {
+		t.Fatalf("expected reused challenge to be rejected, got %v", err)
+	}
+	chain.EnableArtificialFinality(true)
+	challenge, _ = chain.SetArtificialFinalityConfirmed(false, "")
+	chain.afDisableChallenge.expires = time.Now().Add(-time.Second)
+	if _, err := chain.SetArtificialFinalityConfirmed(false, challenge); !errors.Is(err, errAFDisableChallenge) {
+		t.Fatalf("expected expired challenge to be rejected, got %v", err)
+	}
+	if !chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality disabled with an expired challenge")
+	}
+}
+
+func TestArtificialFinalityInactiveReason(t *testing.T) {
+	config := *params.MessNetConfig
+	config.SetECBP1100Transition(u64(100))
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesis.Config = &config
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	if reason := chain.ArtificialFinalityInactiveReason(); reason != "" {
+		t.Fatalf("unexpected reason while disabled: %q", reason)
+	}
+	chain.EnableArtificialFinality(true)
+	for _, c := range []struct {
+		transition, deactivate *uint64
+		want                   string
+	}{
+		{u64(100), nil, "ECBP1100 transition at block 100 not reached, head at block 0"},
+		{nil, nil, "ECBP1100 transition not configured"},
+		{u64(0), u64(0), "ECBP1100 deactivated at block 0, head at block 0"},
+		{u64(0), nil, ""},
+	} {
+		config.SetECBP1100Transition(c.transition)
+		config.SetECBP1100DeactivateTransition(c.deactivate)
+		if reason := chain.ArtificialFinalityInactiveReason(); reason != c.want {
+			t.Errorf("transition %v deactivate %v: reason %q, want %q", c.transition, c.deactivate, reason, c.want)
+		}
+		if cfg := chain.ArtificialFinalityConfig(); cfg.InactiveReason != c.want {
+			t.Errorf("transition %v deactivate %v: config reason %q, want %q", c.transition, c.deactivate, cfg.InactiveReason, c.want)
+		}
+	}
+}
+
+func TestArtificialFinalityTimings(t *testing.T) {
+	for _, c := range []struct {
+		depth  uint64
+		bucket string
+	}{
+		{0, "depth16"},
+		{16, "depth16"},
+		{17, "depth256"},
+		{4096, "depth4096"},
+		{4097, "deep"},
+		{math.MaxUint64, "deep"},
+	} {
+		if name := afEvalDepthBuckets[afEvalDepthBucket(c.depth)].name; name != c.bucket {
+			t.Errorf("depth %d: bucket %q, want %q", c.depth, name, c.bucket)
+		}
+	}
+	timings := (&BlockChain{}).ArtificialFinalityTimings()
+	if len(timings) != len(afEvalDepthBuckets)+1 || timings[0].Bucket != "all" || timings[len(timings)-1].MaxDepth != 0 {
+		t.Errorf("unexpected timings %+v", timings)
+	}
+}
+
+func TestReorgRejectionStats(t *testing.T) {
+	bc := &BlockChain{afRejections: newAFRejectionLog(afRejectionWindow)}
+	if stats := bc.ReorgRejectionStats(); stats.Count != 0 || stats.AgeP99 != 0 || stats.Capacity != afRejectionWindow {
+		t.Fatalf("unexpected empty stats %+v", stats)
+	}
+	ancestor := &types.Header{Number: big.NewInt(100), Time: 1000}
+	// Overflow the window with rejections of ages 1..2*afRejectionWindow, so
+	// only the most recent afRejectionWindow of them are kept.
+	for i := 1; i <= 2*afRejectionWindow; i++ {
+		current := &types.Header{Number: big.NewInt(int64(100 + i%10)), Time: 1000 + uint64(i)}
+		bc.afRejections.add(ancestor, current)
+	}
+	stats := bc.ReorgRejectionStats()
+	want := RejectionStats{
+		Count:    afRejectionWindow,
+		AgeP50:   afRejectionWindow + afRejectionWindow/2,
+		AgeP90:   afRejectionWindow + (90*afRejectionWindow+99)/100,
+		AgeP99:   afRejectionWindow + (99*afRejectionWindow+99)/100,
+		MaxSpan:  9,
+		Capacity: afRejectionWindow,
+	}
+	if stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+}
+
+func TestValidateArtificialFinalityConfig(t *testing.T) {
+	unknownTieBreak := ctypes.ECBP1100TieBreakT(7)
+	for _, c := range []struct {
+		name                   string
+		transition, deactivate *uint64
+		tieBreak               *ctypes.ECBP1100TieBreakT
+		warnMargin             *float64
+		valid                  bool
+	}{
+		{name: "unset", valid: true},
+		{name: "classic", transition: u64(11_380_000), deactivate: u64(19_250_000), valid: true},
+		{name: "deactivation without activation", deactivate: u64(100)},
+		{name: "deactivation at activation", transition: u64(100), deactivate: u64(100)},
+		{name: "deactivation before activation", transition: u64(100), deactivate: u64(99)},
+		{name: "unknown tie break", transition: u64(0), tieBreak: &unknownTieBreak},
+		{name: "warn margin", transition: u64(0), warnMargin: f64(1.1), valid: true},
+		{name: "zero warn margin", transition: u64(0), warnMargin: f64(0)},
+		{name: "negative warn margin", transition: u64(0), warnMargin: f64(-1)},
+		{name: "infinite warn margin", transition: u64(0), warnMargin: f64(math.Inf(1))},
+		{name: "NaN warn margin", transition: u64(0), warnMargin: f64(math.NaN())},
+	} {
+		config := &coregeth.CoreGethChainConfig{}
+		config.SetECBP1100Transition(c.transition)
+		config.SetECBP1100DeactivateTransition(c.deactivate)
+		config.SetECBP1100TieBreak(c.tieBreak)
+		config.SetAFWarnMargin(c.warnMargin)
+		if err := ValidateArtificialFinalityConfig(config); (err == nil) != c.valid {
+			t.Errorf("%s: err=%v, want valid=%v", c.name, err, c.valid)
+		}
+	}
+	// An invalid config is rejected at chain init.
+	config := *params.MessNetConfig
+	config.SetECBP1100DeactivateTransition(u64(1))
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesis.Config = &config
+	if _, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil); err == nil {
+		t.Fatal("expected invalid artificial finality config to be rejected")
+	}
+}
+
+func TestArtificialFinalityConfig(t *testing.T) {
+	// Copy the config, as the settings below would leak into other tests.
+	config := *params.MessNetConfig
+	config.SetECBP1100Transition(u64(0))
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesis.Config = &config
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	maxAge := uint64(3600)
+	favorIncumbent := ctypes.ECBP1100TieBreak_FavorIncumbent
+	chain.Config().SetECBP1100MaxAge(&maxAge)
+	chain.Config().SetECBP1100TieBreak(&favorIncumbent)
+	chain.EnableArtificialFinality(true)
+	chain.ArtificialFinalityNoDisable(1)
+	chain.Config().SetAFWarnMargin(f64(1.5))
+	chain.SetArtificialFinalityLogLevel(gethlog.LvlDebug)
+	chain.WhitelistReorgTarget(common.Hash{0x01})
+
+	cfg := chain.ArtificialFinalityConfig()
+	if !cfg.Enabled || !cfg.NoDisable || !cfg.Active {
+		t.Errorf("unexpected status: enabled=%v nodisable=%v active=%v", cfg.Enabled, cfg.NoDisable, cfg.Active)
+	}
+	if cfg.ECBP1100Transition == nil || *cfg.ECBP1100Transition != 0 {
+		t.Errorf("transition = %v, want 0", cfg.ECBP1100Transition)
+	}
+	if cfg.MaxAge == nil || *cfg.MaxAge != maxAge {
+		t.Errorf("max age = %v, want %d", cfg.MaxAge, maxAge)
+	}
+	if cfg.TieBreak != favorIncumbent || cfg.Curve != "polynomialV" {
+		t.Errorf("tie break = %v, curve = %q", cfg.TieBreak, cfg.Curve)
+	}
+	if cfg.MarginWarnThreshold != 1.5 || cfg.LogLevel != gethlog.LvlDebug.String() {
+		t.Errorf("margin warn threshold = %v, log level = %q", cfg.MarginWarnThreshold, cfg.LogLevel)
+	}
+	if len(cfg.ReorgWhitelist) != 1 || cfg.ReorgWhitelist[0] != (common.Hash{0x01}) {
+		t.Errorf("reorg whitelist = %v", cfg.ReorgWhitelist)
+	}
+}
+
+// noHeaders is a header lookup for tests evaluating MESS on detached headers.
+func noHeaders(common.Hash, uint64) *types.Header { return nil }
+
+func TestMESSCurveDenominator(t *testing.T) {
+	if d := messCurvePolynomialV.denominator.Int64(); d != 128 {
+		t.Fatalf("polynomial curve denominator %d, want 128", d)
+	}
+	// A flat curve requiring the proposed subchain to be 1.5 times heavier,
+	// expressed with a denominator other than 128.
+	flat := &messCurve{
+		name:        "flat",
+		numerator:   func(*big.Int) *big.Int { return big.NewInt(3) },
+		denominator: big.NewInt(2),
+	}
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(20), Time: 1000, Difficulty: big.NewInt(1)}
+	for _, c := range []struct {
+		proposedParentTD int64
+		rejected         bool
+	}{
+		{1148, true},
+		{1149, false},
+	} {
+		proposed := &types.Header{Number: big.NewInt(21), ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}
+		getTD := func(hash common.Hash, n uint64) *big.Int {
+			switch hash {
+			case commonAncestor.Hash():
+				return big.NewInt(1000)
+			case current.Hash():
+				return big.NewInt(1100)
+			}
+			return big.NewInt(c.proposedParentTD)
+		}
+		err := ecbp1100WithCurve(gethlog.Root(), params.MessNetConfig, flat, commonAncestor, current, proposed, getTD, noHeaders, nil)
+		if rejected := errors.Is(err, errReorgFinality); rejected != c.rejected {
+			t.Errorf("proposed parent td %d: rejected=%v, want %v", c.proposedParentTD, rejected, c.rejected)
+		}
+	}
+}
+
 // TestEcbp1100PolynomialV tests the general shape and return values of the ECBP1100 polynomial curve.
 // It makes sure domain values above the 'cap' do indeed get limited, as well
 // as sanity check some normal domain values.
@@ -270,6 +1537,453 @@ func TestEcbp1100PolynomialV(t *testing.T) {
 	}
 }
 
+// TestEcbp1100ZeroSubchainTD tests that a tie between zero-valued subchain
+// total difficulties is allowed, and that the allow path's debug log tolerates
+// the undefined ratio.
+func TestEcbp1100ZeroSubchainTD(t *testing.T) {
+	defer gethlog.Root().SetHandler(gethlog.Root().GetHandler())
+	gethlog.Root().SetHandler(gethlog.LvlFilterHandler(gethlog.LvlDebug, gethlog.StreamHandler(io.Discard, gethlog.TerminalFormat(false))))
+
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 100, Difficulty: new(big.Int)}
+	proposed := &types.Header{Number: big.NewInt(11), ParentHash: commonAncestor.Hash(), Time: 113, Difficulty: new(big.Int)}
+	getTD := func(common.Hash, uint64) *big.Int { return big.NewInt(1000) }
+
+	if err := ecbp1100(gethlog.Root(), params.MessNetConfig, commonAncestor, commonAncestor, proposed, getTD, noHeaders); err != nil {
+		t.Fatalf("expected tie to be allowed, got %v", err)
+	}
+}
+
+// TestEcbp1100MaxAge tests that reorgs older than the configured maximum age are
+// rejected regardless of their total difficulty.
+func TestEcbp1100MaxAge(t *testing.T) {
+	config := &coregeth.CoreGethChainConfig{}
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(60), Time: 1500, Difficulty: big.NewInt(1)}
+	proposed := &types.Header{Number: big.NewInt(61), ParentHash: common.Hash{0x01}, Time: 1510, Difficulty: big.NewInt(1)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		switch hash {
+		case commonAncestor.Hash():
+			return big.NewInt(1000)
+		case current.Hash():
+			return big.NewInt(1050)
+		}
+		// An overwhelmingly heavier proposed segment.
+		return big.NewInt(20_000)
+	}
+	for _, c := range []struct {
+		maxAge   *uint64
+		rejected bool
+	}{
+		{nil, false},
+		{u64(0), false},
+		{u64(500), false},
+		{u64(499), true},
+	} {
+		var buf bytes.Buffer
+		logger := gethlog.New()
+		logger.SetHandler(gethlog.LvlFilterHandler(gethlog.LvlWarn, gethlog.StreamHandler(&buf, gethlog.JSONFormat())))
+
+		config.SetECBP1100MaxAge(c.maxAge)
+		err := ecbp1100(logger, config, commonAncestor, current, proposed, getTD, noHeaders)
+		if rejected := errors.Is(err, errReorgFinality); rejected != c.rejected {
+			t.Errorf("maxAge=%v: rejected=%v, want %v (err=%v)", c.maxAge, rejected, c.rejected, err)
+		}
+		// Max-age rejections are logged like curve rejections, with a reason.
+		if !c.rejected {
+			if buf.Len() != 0 {
+				t.Errorf("maxAge=%v: unexpected warning: %s", c.maxAge, buf.String())
+			}
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("maxAge=%v: rejection not logged as a single JSON record: %v\n%s", c.maxAge, err, buf.String())
+		}
+		if record["reason"] != "max-age" || record["age"] != float64(500) || record["max_age"] != float64(*c.maxAge) {
+			t.Errorf("maxAge=%v: unexpected rejection record: %s", c.maxAge, buf.String())
+		}
+	}
+}
+
+// TestEcbp1100TipGrace tests that reorgs within the tip grace are exempt from
+// the MESS curve, but still subject to the maximum age and difficulty checks.
+func TestEcbp1100TipGrace(t *testing.T) {
+	config := &coregeth.CoreGethChainConfig{}
+	commonAncestor := &types.Header{Number: big.NewInt(19), Time: 1000, Difficulty: big.NewInt(10)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		if hash == commonAncestor.Hash() {
+			return big.NewInt(1000)
+		}
+		if hash == (common.Hash{0x01}) {
+			return big.NewInt(1004)
+		}
+		return big.NewInt(1010)
+	}
+	// The proposed segment is lighter than the current one, so MESS would
+	// reject the reorg if it were evaluated.
+	for _, c := range []struct {
+		grace      *uint64
+		maxAge     *uint64
+		depth      int64
+		difficulty int64
+		rejected   bool
+	}{
+		{nil, nil, 1, 5, false},
+		{nil, nil, DefaultMESSTipGrace, 5, false},
+		{nil, nil, DefaultMESSTipGrace + 1, 5, true},
+		{u64(0), nil, 1, 5, true},
+		{u64(1), nil, 1, 5, false},
+		{u64(1), nil, 2, 5, true},
+		// The grace doesn't bypass the sanity checks ahead of the curve.
+		{nil, u64(10), 1, 5, true},
+		{nil, nil, 1, 0, true},
+	} {
+		config.SetMESSTipGrace(c.grace)
+		config.SetECBP1100MaxAge(c.maxAge)
+		current := &types.Header{Number: big.NewInt(19 + c.depth), Time: 1013, Difficulty: big.NewInt(10)}
+		proposed := &types.Header{Number: big.NewInt(20 + c.depth), ParentHash: common.Hash{0x01}, Time: 1014, Difficulty: big.NewInt(c.difficulty)}
+		err := ecbp1100(gethlog.Root(), config, commonAncestor, current, proposed, getTD, noHeaders)
+		if rejected := errors.Is(err, errReorgFinality); rejected != c.rejected {
+			t.Errorf("grace=%v maxAge=%v depth=%d difficulty=%d: rejected=%v, want %v (err=%v)", c.grace, c.maxAge, c.depth, c.difficulty, rejected, c.rejected, err)
+		}
+	}
+}
+
+func TestMESSMargin(t *testing.T) {
+	// With an age of zero the curve requires the proposed subchain TD to at
+	// least match the local one, so the margin is the plain TD ratio.
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(20), Time: 1000, Difficulty: big.NewInt(1)}
+	for _, c := range []struct {
+		proposedParentTD int64
+		margin           float64
+	}{
+		{1099, 1.0},
+		{1049, 0.5},
+		{1199, 2.0},
+	} {
+		proposed := &types.Header{Number: big.NewInt(21), ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}
+		getTD := func(hash common.Hash, n uint64) *big.Int {
+			switch hash {
+			case commonAncestor.Hash():
+				return big.NewInt(1000)
+			case current.Hash():
+				return big.NewInt(1100)
+			}
+			return big.NewInt(c.proposedParentTD)
+		}
+		ops, err := ecbp1100Operands(messCurvePolynomialV, commonAncestor, current, proposed, getTD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if margin := ops.margin(); margin != c.margin {
+			t.Errorf("proposed parent td %d: margin %v, want %v", c.proposedParentTD, margin, c.margin)
+		}
+		err = ecbp1100(gethlog.Root(), params.MessNetConfig, commonAncestor, current, proposed, getTD, noHeaders)
+		if rejected := errors.Is(err, errReorgFinality); rejected != (c.margin < 1) {
+			t.Errorf("proposed parent td %d: rejected=%v with margin %v", c.proposedParentTD, rejected, c.margin)
+		}
+	}
+	// Unknown total difficulties are reported rather than dereferenced.
+	missing := func(common.Hash, uint64) *big.Int { return nil }
+	if _, err := ecbp1100Operands(messCurvePolynomialV, commonAncestor, current, current, missing); err == nil {
+		t.Error("expected error for missing total difficulty")
+	}
+}
+
+// TestRequiredProposedTD tests that the estimated proposed subchain TD is the
+// smallest one allowed by MESS across the curve.
+func TestRequiredProposedTD(t *testing.T) {
+	for _, age := range []uint64{0, 100, 1300, 6500, 13000, 25132, 1e6} {
+		for _, local := range []int64{1, 1000, 12345, 1e15} {
+			localSubchainTD := big.NewInt(local)
+			required := RequiredProposedTD(age, localSubchainTD)
+
+			commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+			current := &types.Header{Number: big.NewInt(60), Time: 1000 + age, Difficulty: big.NewInt(1)}
+			proposed := &types.Header{Number: big.NewInt(61), ParentHash: common.Hash{0x01}, Time: 1010 + age, Difficulty: big.NewInt(1)}
+			for _, c := range []struct {
+				proposedSubchainTD *big.Int
+				rejected           bool
+			}{
+				{required, false},
+				{new(big.Int).Sub(required, big.NewInt(1)), true},
+			} {
+				getTD := func(hash common.Hash, n uint64) *big.Int {
+					switch hash {
+					case commonAncestor.Hash():
+						return big.NewInt(1000)
+					case current.Hash():
+						return new(big.Int).Add(big.NewInt(1000), localSubchainTD)
+					}
+					// The proposed block adds a difficulty of 1 to its parent.
+					td := new(big.Int).Add(big.NewInt(1000), c.proposedSubchainTD)
+					return td.Sub(td, big.NewInt(1))
+				}
+				ops, err := ecbp1100Operands(messCurvePolynomialV, commonAncestor, current, proposed, getTD)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rejected := ops.got.Cmp(ops.want) < 0; rejected != c.rejected {
+					t.Errorf("age=%d local=%d proposed=%v: rejected=%v, want %v (got=%v want=%v)",
+						age, local, c.proposedSubchainTD, rejected, c.rejected, ops.got, ops.want)
+				}
+			}
+		}
+	}
+	// Past xcap the curve is capped at 31x the local subchain TD.
+	if got, want := RequiredProposedTD(1e6, big.NewInt(1000)), big.NewInt(31_000); got.Cmp(want) != 0 {
+		t.Errorf("capped required proposed td: got %v, want %v", got, want)
+	}
+	// Missing or negative local subchain TDs have no requirement.
+	for _, local := range []*big.Int{nil, big.NewInt(-1)} {
+		if got := RequiredProposedTD(100, local); got != nil {
+			t.Errorf("local subchain td %v: got %v, want nil", local, got)
+		}
+	}
+}
+
+func TestSegmentComparison(t *testing.T) {
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(20), Time: 1000, Difficulty: big.NewInt(1)}
+	proposed := &types.Header{Number: big.NewInt(21), Time: 1010, ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		switch hash {
+		case commonAncestor.Hash():
+			return big.NewInt(1000)
+		case current.Hash():
+			return big.NewInt(1100)
+		}
+		return big.NewInt(1049)
+	}
+	// The comparison is filled in for rejected reorgs too.
+	var cmp SegmentComparison
+	if err := ecbp1100WithCurve(gethlog.Root(), params.MessNetConfig, messCurvePolynomialV, commonAncestor, current, proposed, getTD, noHeaders, &cmp); !errors.Is(err, errReorgFinality) {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+	want := SegmentComparison{
+		CommonAncestor:     SegmentHead{Number: 10, Hash: commonAncestor.Hash(), Time: 1000, TD: big.NewInt(1000)},
+		Current:            SegmentHead{Number: 20, Hash: current.Hash(), Time: 1000, TD: big.NewInt(1100)},
+		Proposed:           SegmentHead{Number: 21, Hash: proposed.Hash(), Time: 1010, TD: big.NewInt(1050)},
+		ProposedSpan:       10,
+		LocalSubchainTD:    big.NewInt(100),
+		ProposedSubchainTD: big.NewInt(50),
+		Margin:             0.5,
+	}
+	if !reflect.DeepEqual(cmp, want) {
+		t.Errorf("got %+v, want %+v", cmp, want)
+	}
+	// Without the total difficulties there is nothing to compare.
+	cmp = SegmentComparison{}
+	missing := func(common.Hash, uint64) *big.Int { return nil }
+	if err := ecbp1100WithCurve(gethlog.Root(), params.MessNetConfig, messCurvePolynomialV, commonAncestor, current, proposed, missing, noHeaders, &cmp); err == nil {
+		t.Error("expected error for missing total difficulty")
+	}
+	if cmp.LocalSubchainTD != nil {
+		t.Errorf("comparison filled without total difficulties: %+v", cmp)
+	}
+}
+
+func TestEcbp1100TieBreak(t *testing.T) {
+	// Age zero and equal subchain TDs make got == want exactly.
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(20), Time: 1000, Difficulty: big.NewInt(1)}
+	proposed := &types.Header{Number: big.NewInt(21), ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		switch {
+		case n == commonAncestor.Number.Uint64():
+			return big.NewInt(1000)
+		case hash == proposed.ParentHash:
+			return big.NewInt(1099)
+		}
+		return big.NewInt(1100)
+	}
+	favorProposed, favorIncumbent := ctypes.ECBP1100TieBreak_FavorProposed, ctypes.ECBP1100TieBreak_FavorIncumbent
+	config := &coregeth.CoreGethChainConfig{}
+	for _, c := range []struct {
+		tieBreak *ctypes.ECBP1100TieBreakT
+		rejected bool
+	}{
+		{nil, false},
+		{&favorProposed, false},
+		{&favorIncumbent, true},
+	} {
+		config.SetECBP1100TieBreak(c.tieBreak)
+		err := ecbp1100(gethlog.Root(), config, commonAncestor, current, proposed, getTD, noHeaders)
+		if rejected := errors.Is(err, errReorgFinalityMESS); rejected != c.rejected {
+			t.Errorf("tie break %v: rejected=%v, want %v (err=%v)", c.tieBreak, rejected, c.rejected, err)
+		}
+	}
+}
+
+func TestEcbp1100TimestampInversion(t *testing.T) {
+	// The current head claims to be older than the common ancestor.
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 5000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(20), Time: 4000, Difficulty: big.NewInt(1)}
+	proposed := &types.Header{Number: big.NewInt(21), Time: 5100, ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		if n == commonAncestor.Number.Uint64() {
+			return big.NewInt(1000)
+		}
+		return big.NewInt(100000)
+	}
+	err := ecbp1100(gethlog.Root(), &coregeth.CoreGethChainConfig{}, commonAncestor, current, proposed, getTD, noHeaders)
+	if !errors.Is(err, errReorgFinality) || !strings.Contains(err.Error(), "timestamp-inversion") {
+		t.Fatalf("expected timestamp inversion rejection, got %v", err)
+	}
+	if _, err := ecbp1100Operands(messCurvePolynomialV, commonAncestor, current, proposed, getTD); err == nil {
+		t.Fatal("expected error computing operands for inverted timestamps")
+	}
+}
+
+// TestEcbp1100ImplausibleDifficulty tests that a reorg is rejected if any block
+// of the proposed segment, not only its tip, has an implausible difficulty.
+func TestEcbp1100ImplausibleDifficulty(t *testing.T) {
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(4096)}
+	current := &types.Header{Number: big.NewInt(20), Time: 1000, Difficulty: big.NewInt(4096)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		if n == commonAncestor.Number.Uint64() {
+			return big.NewInt(1_000_000)
+		}
+		// Far heavier than the local subchain, so only the sanity check can reject.
+		return big.NewInt(1_200_000)
+	}
+	for _, c := range []struct {
+		tip, parent int64
+		rejected    bool
+	}{
+		{0, 4096, true},
+		{1, 4096, true},
+		{4096, 4096, false},
+		{4096 * 1025, 4096, true},
+		// The average of the segment is plausible, but not its middle block.
+		{8192, 0, true},
+		{8192, 1, true},
+	} {
+		parent := &types.Header{Number: big.NewInt(11), ParentHash: commonAncestor.Hash(), Difficulty: big.NewInt(c.parent)}
+		proposed := &types.Header{Number: big.NewInt(12), ParentHash: parent.Hash(), Difficulty: big.NewInt(c.tip)}
+		getHeader := func(hash common.Hash, n uint64) *types.Header {
+			if hash == parent.Hash() {
+				return parent
+			}
+			return nil
+		}
+		err := ecbp1100(gethlog.Root(), &coregeth.CoreGethChainConfig{}, commonAncestor, current, proposed, getTD, getHeader)
+		if rejected := errors.Is(err, errReorgFinalityMESS) && strings.Contains(err.Error(), "implausible-difficulty"); rejected != c.rejected {
+			t.Errorf("tip difficulty %d, parent difficulty %d: rejected=%v, want %v (err=%v)", c.tip, c.parent, rejected, c.rejected, err)
+		}
+	}
+}
+
+func TestEcbp1100InvalidSegment(t *testing.T) {
+	genesis := &types.Header{Number: big.NewInt(0), Time: 1000, Difficulty: big.NewInt(1)}
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(20), Time: 1100, Difficulty: big.NewInt(1)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		if n > 1_000_000 {
+			t.Fatalf("total difficulty requested at block %d", n)
+		}
+		return big.NewInt(1000)
+	}
+	for _, c := range []struct {
+		name                     string
+		commonAncestor, proposed *types.Header
+	}{
+		{"genesis", genesis, &types.Header{Number: big.NewInt(0), Time: 1000, Difficulty: big.NewInt(1)}},
+		{"at ancestor", commonAncestor, &types.Header{Number: big.NewInt(10), ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}},
+		{"below ancestor", commonAncestor, &types.Header{Number: big.NewInt(5), ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}},
+	} {
+		err := ecbp1100(gethlog.Root(), &coregeth.CoreGethChainConfig{}, c.commonAncestor, current, c.proposed, getTD, noHeaders)
+		if !errors.Is(err, errReorgFinalityMESS) || !strings.Contains(err.Error(), "invalid-segment") {
+			t.Errorf("%s: expected invalid segment rejection, got %v", c.name, err)
+		}
+	}
+}
+
+func TestEcbp1100RejectionLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := gethlog.New()
+	logger.SetHandler(gethlog.StreamHandler(&buf, gethlog.JSONFormat()))
+
+	// An hour old common ancestor and equal subchain TDs are well below the curve.
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(20), Time: 4600, Difficulty: big.NewInt(1)}
+	proposed := &types.Header{Number: big.NewInt(21), Time: 4600, ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		if n == commonAncestor.Number.Uint64() {
+			return big.NewInt(1000)
+		}
+		return big.NewInt(1100)
+	}
+	err := ecbp1100(logger, &coregeth.CoreGethChainConfig{}, commonAncestor, current, proposed, getTD, noHeaders)
+	if !errors.Is(err, errReorgFinality) {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("rejection not logged as a single JSON record: %v\n%s", err, buf.String())
+	}
+	for _, key := range []string{"age", "current_span", "proposed_span", "ratio", "common_bno", "common_hash", "current_bno", "current_hash", "proposed_bno", "proposed_hash"} {
+		if _, ok := record[key]; !ok {
+			t.Errorf("rejection log missing field %q: %s", key, buf.String())
+		}
+	}
+	if record["current_span"] != float64(3600) {
+		t.Errorf("current_span = %v, want 3600", record["current_span"])
+	}
+}
+
+func f64(val float64) *float64 { return &val }
+
+func TestEcbp1100NearThresholdLog(t *testing.T) {
+	// Age zero makes the margin the plain subchain TD ratio, here 1.05.
+	commonAncestor := &types.Header{Number: big.NewInt(10), Time: 1000, Difficulty: big.NewInt(1)}
+	current := &types.Header{Number: big.NewInt(20), Time: 1000, Difficulty: big.NewInt(1)}
+	proposed := &types.Header{Number: big.NewInt(21), Time: 1000, ParentHash: common.Hash{0x01}, Difficulty: big.NewInt(1)}
+	getTD := func(hash common.Hash, n uint64) *big.Int {
+		switch hash {
+		case commonAncestor.Hash():
+			return big.NewInt(1000)
+		case current.Hash():
+			return big.NewInt(1100)
+		}
+		return big.NewInt(1104)
+	}
+	for _, c := range []struct {
+		warnMargin *float64
+		logged     bool
+	}{
+		{nil, true}, // DefaultMESSMarginWarnThreshold
+		{f64(1.01), false},
+		{f64(1.1), true},
+	} {
+		var buf bytes.Buffer
+		logger := gethlog.New()
+		logger.SetHandler(gethlog.LvlFilterHandler(gethlog.LvlWarn, gethlog.StreamHandler(&buf, gethlog.JSONFormat())))
+
+		config := &coregeth.CoreGethChainConfig{}
+		config.SetAFWarnMargin(c.warnMargin)
+		if err := ecbp1100(logger, config, commonAncestor, current, proposed, getTD, noHeaders); err != nil {
+			t.Fatalf("expected reorg to be allowed, got %v", err)
+		}
+		if logged := buf.Len() > 0; logged != c.logged {
+			t.Fatalf("warn margin %v: logged=%v, want %v: %s", c.warnMargin, logged, c.logged, buf.String())
+		}
+		if !c.logged {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"got", "want", "ratio"} {
+			if _, ok := record[key]; !ok {
+				t.Errorf("near-threshold log missing field %q: %s", key, buf.String())
+			}
+		}
+	}
+}
+
 func TestPlot_ecbp1100PolynomialV(t *testing.T) {
 	t.Skip("This test plots a graph of the ECBP1100 polynomial curve.")
 	p := plot.New()
@@ -313,6 +2027,79 @@ func TestEcbp1100AGSinusoidalA(t *testing.T) {
 	}
 }
 
+func TestEcbp1100AGExp(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		fn   func(float64) float64
+	}{
+		{"expA", ecbp1100AGExpA},
+		{"expB", ecbp1100AGExpB},
+	} {
+		for _, x := range []float64{math.Inf(-1), -1e9, -1, 0, 0.3, 1, 1e9, math.MaxFloat64, math.Inf(1), math.NaN()} {
+			got := c.fn(x)
+			if math.IsNaN(got) || math.IsInf(got, 0) || got < 1 || got > ecbp1100AGCeiling {
+				t.Errorf("%s(%v) = %v, want a value in [1, %d]", c.name, x, got, ecbp1100AGCeiling)
+			}
+		}
+		if got := c.fn(0); got != 1 {
+			t.Errorf("%s(0) = %v, want 1", c.name, got)
+		}
+		if got := c.fn(1e9); got != ecbp1100AGCeiling {
+			t.Errorf("%s(1e9) = %v, want the ceiling %d", c.name, got, ecbp1100AGCeiling)
+		}
+		// The clamped curves must not decrease.
+		prev := c.fn(-1)
+		for x := float64(0); x <= 1e6; x += 0.25 + x/100 {
+			got := c.fn(x)
+			if got < prev {
+				t.Fatalf("%s decreases at x=%v: %v < %v", c.name, x, got, prev)
+			}
+			prev = got
+		}
+	}
+}
+
+func TestEcbp1100SinusoidalV(t *testing.T) {
+	// Exact values; any platform must reproduce these.
+	cases := []struct {
+		x    int64
+		want int64
+	}{
+		{0, 128},
+		{60, 128},
+		{600, 133},
+		{3600, 319},
+		{8000, 1010},
+		{12566, 2047},
+		{20000, 3586},
+		{25132, 3967},
+		{25133, 3967},
+		{100000, 3967},
+	}
+	for _, c := range cases {
+		x := big.NewInt(c.x)
+		if got := ecbp1100SinusoidalV(x); got.Int64() != c.want {
+			t.Errorf("x=%d: got %v, want %d", c.x, got, c.want)
+		}
+		if x.Int64() != c.x {
+			t.Errorf("x=%d: argument mutated to %v", c.x, x)
+		}
+	}
+	// The curve is monotonic and tracks its floating point counterpart.
+	prev := big.NewInt(0)
+	for x := int64(0); x <= 26000; x += 13 {
+		got := ecbp1100SinusoidalV(big.NewInt(x))
+		if got.Cmp(prev) < 0 {
+			t.Fatalf("x=%d: curve decreased from %v to %v", x, prev, got)
+		}
+		prev = got
+		want := ecbp1100AGSinusoidalA(float64(x)) * float64(ecbp1100PolynomialVCurveFunctionDenominator.Int64())
+		if diff := want - float64(got.Int64()); diff < 0 || diff > 1 {
+			t.Fatalf("x=%d: got %v, float curve %0.6f", x, got, want)
+		}
+	}
+}
+
 func TestDifficultyDelta(t *testing.T) {
 	t.Skip("A development test to play with difficulty steps.")
 	parent := &types.Header{
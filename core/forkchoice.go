@@ -22,9 +22,11 @@ import (
 	"fmt"
 	"math/big"
 	mrand "math/rand"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -45,6 +47,10 @@ type ForkChoice struct {
 	// local td is equal to the extern one. It can be nil for light
 	// client
 	preserve func(header *types.Header) bool
+
+	// ancestors caches common ancestors computed for artificial finality, so
+	// that bursts of competing blocks don't repeat the same ancestor walk.
+	ancestors *ancestorCache
 }
 
 func NewForkChoice(chainReader consensus.ChainHeaderReader, preserve func(header *types.Header) bool) *ForkChoice {
@@ -54,45 +60,54 @@ func NewForkChoice(chainReader consensus.ChainHeaderReader, preserve func(header
 		log.Crit("Failed to initialize random seed", "err", err)
 	}
 	return &ForkChoice{
-		chain:    chainReader,
-		rand:     mrand.New(mrand.NewSource(seed.Int64())),
-		preserve: preserve,
+		chain:     chainReader,
+		rand:      mrand.New(mrand.NewSource(seed.Int64())),
+		preserve:  preserve,
+		ancestors: newAncestorCache(ancestorCacheLimit, ancestorCacheTTL),
 	}
 }
 
+// CommonAncestor walks the chains ending at current and header back through the
+// header lookups of the chain reader until they meet, returning the fork point.
+// It errors if either side runs out of available headers first.
 func (f *ForkChoice) CommonAncestor(current *types.Header, header *types.Header) (*types.Header, error) {
 	oldH, newH := types.CopyHeader(current), types.CopyHeader(header)
-	var commonAncestor *types.Header
+
+	// parent returns the parent of h, reporting the block at which the walk
+	// ran out of headers otherwise.
+	parent := func(side string, h *types.Header) (*types.Header, error) {
+		if h.Number.Sign() > 0 {
+			if p := f.chain.GetHeader(h.ParentHash, h.Number.Uint64()-1); p != nil {
+				return p, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid %s chain: missing parent of block %d [%x]", side, h.Number.Uint64(), h.Hash())
+	}
+	var err error
 
 	// Reduce the longer chain to the same number as the shorter one.
-	if oldH.Number.Uint64() > newH.Number.Uint64() {
-		for ; oldH != nil && oldH.Number.Uint64() != newH.Number.Uint64(); oldH = f.chain.GetHeader(oldH.ParentHash, oldH.Number.Uint64()-1) {
-			// noop (txes and logs aggregation not handled here)
+	for oldH.Number.Uint64() > newH.Number.Uint64() {
+		if oldH, err = parent("oldH", oldH); err != nil {
+			return nil, err
 		}
-	} else {
-		for ; newH != nil && newH.Number.Uint64() != oldH.Number.Uint64(); newH = f.chain.GetHeader(newH.ParentHash, newH.Number.Uint64()-1) {
-			// noop
+	}
+	for newH.Number.Uint64() > oldH.Number.Uint64() {
+		if newH, err = parent("newH", newH); err != nil {
+			return nil, err
 		}
 	}
 
 	// Both sides of the reorg are at the same number, reduce both until the
 	// common ancestor is found.
-	for {
-		if oldH.Hash() == newH.Hash() {
-			commonAncestor = oldH
-			break
+	for oldH.Hash() != newH.Hash() {
+		if oldH, err = parent("oldH", oldH); err != nil {
+			return nil, err
 		}
-		oldH = f.chain.GetHeader(oldH.ParentHash, oldH.Number.Uint64()-1)
-		if oldH == nil {
-			return nil, fmt.Errorf("invalid oldH chain")
-		}
-
-		newH = f.chain.GetHeader(newH.ParentHash, newH.Number.Uint64()-1)
-		if newH == nil {
-			return nil, fmt.Errorf("invalid newH chain")
+		if newH, err = parent("newH", newH); err != nil {
+			return nil, err
 		}
 	}
-	return commonAncestor, nil
+	return oldH, nil
 }
 
 // ReorgNeeded returns whether the reorg should be applied
@@ -100,6 +115,11 @@ func (f *ForkChoice) CommonAncestor(current *types.Header, header *types.Header)
 // In the td mode, the new head is chosen if the corresponding
 // total difficulty is higher. In the extern mode, the trusted
 // header is always selected as the head.
+//
+// Once artificial finality applies, a reorg whose common ancestor with the
+// local chain can't be found (e.g. as the fork point was pruned) is rejected
+// rather than reported as an error: false is returned with a nil error, and
+// the failed lookup is logged.
 func (f *ForkChoice) ReorgNeeded(current *types.Header, extern *types.Header) (bool, error) {
 	var (
 		localTD  = f.chain.GetTd(current.Hash(), current.Number.Uint64())
@@ -166,17 +186,25 @@ func (f *ForkChoice) ReorgNeeded(current *types.Header, extern *types.Header) (b
 		return reorg, nil
 	}
 
-	commonHeader, err := f.CommonAncestor(current, extern)
+	commonHeader, err := f.cachedCommonAncestor(current, extern)
 	if err != nil {
-		return reorg, err
+		// The fork point may have been pruned; the reorg is rejected below.
+		f.afLimitedLogger(nil).Warn("ECBP1100-MESS common ancestor not found", "current.bno", current.Number.Uint64(), "current.hash", current.Hash(),
+			"proposed.bno", extern.Number.Uint64(), "proposed.hash", extern.Hash(), "boundary", err)
+		commonHeader = nil
 	}
 
-	if err := ecbp1100(commonHeader, current, extern, f.chain.GetTd); err != nil {
+	if err := f.evaluateArtificialFinality(commonHeader, current, extern); err != nil {
 		reorg = false
-		log.Warn("Reorg disallowed", "error", err)
+		// MESS has already warned about its own rejections.
+		if errors.Is(err, errReorgFinalityMESS) {
+			f.afLimitedLogger(commonHeader).Debug("Reorg disallowed", "error", err)
+		} else {
+			f.afLimitedLogger(commonHeader).Warn("Reorg disallowed", "error", err)
+		}
 	} else if current.Number.Uint64()-commonHeader.Number.Uint64() > 2 {
 		// Reorg is allowed, only log the MESS line if old chain is longer than normal.
-		log.Info("ECBP1100-MESS 🔓",
+		f.afLogger().Info("ECBP1100-MESS 🔓",
 			"status", "accepted",
 			"age", common.PrettyAge(time.Unix(int64(commonHeader.Time), 0)),
 			"current.span", common.PrettyDuration(time.Duration(current.Time-commonHeader.Time)*time.Second),
@@ -189,3 +217,114 @@ func (f *ForkChoice) ReorgNeeded(current *types.Header, extern *types.Header) (b
 
 	return reorg, nil
 }
+
+// evaluateArtificialFinality runs the artificial finality checks for a reorg
+// from current to proposed. Full blockchains apply their own operator settings
+// on top of MESS; other chain readers fall back to plain MESS.
+func (f *ForkChoice) evaluateArtificialFinality(commonAncestor, current, proposed *types.Header) error {
+	if commonAncestor == nil {
+		return newErrNoCommonAncestor(current, proposed)
+	}
+	// A proposed chain extending the head is not a reorg; skip the evaluation.
+	if commonAncestor.Hash() == current.Hash() {
+		return nil
+	}
+	if bc, ok := f.chain.(*BlockChain); ok {
+		return bc.evaluateArtificialFinality(commonAncestor, current, proposed)
+	}
+	return ecbp1100(log.Root(), f.chain.Config(), commonAncestor, current, proposed, f.chain.GetTd, f.chain.GetHeader)
+}
+
+// afLimitedLogger returns the logger for artificial finality decisions on a
+// reorg forking at commonAncestor, rate limiting repeated warnings.
+func (f *ForkChoice) afLimitedLogger(commonAncestor *types.Header) log.Logger {
+	if bc, ok := f.chain.(*BlockChain); ok {
+		return bc.afLimitedLogger(commonAncestor)
+	}
+	return log.Root()
+}
+
+// afLogger returns the logger for artificial finality decisions.
+func (f *ForkChoice) afLogger() log.Logger {
+	if bc, ok := f.chain.(*BlockChain); ok {
+		return bc.afLogger
+	}
+	return log.Root()
+}
+
+// cachedCommonAncestor is CommonAncestor backed by the ancestor cache. Since the
+// proposed header is never an ancestor of the current head when a reorg is
+// considered, a block shares its common ancestor with its parent, which lets a
+// new block extending a competing chain reuse the result of its parent.
+func (f *ForkChoice) cachedCommonAncestor(current, proposed *types.Header) (*types.Header, error) {
+	if ancestor := f.ancestors.get(current.Hash(), proposed.Hash()); ancestor != nil {
+		return ancestor, nil
+	}
+	if ancestor := f.ancestors.get(current.Hash(), proposed.ParentHash); ancestor != nil {
+		f.ancestors.add(current.Hash(), proposed.Hash(), ancestor)
+		return ancestor, nil
+	}
+	ancestor, err := f.CommonAncestor(current, proposed)
+	if err != nil {
+		return nil, err
+	}
+	f.ancestors.add(current.Hash(), proposed.Hash(), ancestor)
+	return ancestor, nil
+}
+
+const (
+	ancestorCacheLimit = 256
+	ancestorCacheTTL   = 10 * time.Minute
+)
+
+type ancestorCacheEntry struct {
+	ancestor *types.Header
+	added    time.Time
+}
+
+// ancestorCache maps proposed block hashes to their common ancestor with the
+// current head. All entries refer to a single head; the cache is purged when
+// queried for a different one.
+type ancestorCache struct {
+	mu    sync.Mutex
+	head  common.Hash
+	items lru.BasicLRU[common.Hash, ancestorCacheEntry]
+	ttl   time.Duration
+}
+
+func newAncestorCache(limit int, ttl time.Duration) *ancestorCache {
+	return &ancestorCache{items: lru.NewBasicLRU[common.Hash, ancestorCacheEntry](limit), ttl: ttl}
+}
+
+// setHead purges the cache if head differs from the head of the cached entries.
+// The caller must hold c.mu.
+func (c *ancestorCache) setHead(head common.Hash) {
+	if c.head != head {
+		c.items.Purge()
+		c.head = head
+	}
+}
+
+func (c *ancestorCache) get(head, proposed common.Hash) *types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setHead(head)
+	entry, ok := c.items.Get(proposed)
+	if !ok {
+		return nil
+	}
+	if time.Since(entry.added) > c.ttl {
+		c.items.Remove(proposed)
+		return nil
+	}
+	return entry.ancestor
+}
+
+func (c *ancestorCache) add(head, proposed common.Hash, ancestor *types.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setHead(head)
+	c.items.Add(proposed, ancestorCacheEntry{ancestor: ancestor, added: time.Now()})
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadReorgWhitelist retrieves the block hashes exempted from artificial
+// finality checks by manual operator intervention.
+func ReadReorgWhitelist(db ethdb.KeyValueReader) []common.Hash {
+	data, _ := db.Get(reorgWhitelistKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var hashes []common.Hash
+	if err := rlp.DecodeBytes(data, &hashes); err != nil {
+		log.Error("Invalid reorg whitelist RLP", "err", err)
+		return nil
+	}
+	return hashes
+}
+
+// WriteReorgWhitelist stores the block hashes exempted from artificial
+// finality checks.
+func WriteReorgWhitelist(db ethdb.KeyValueWriter, hashes []common.Hash) {
+	data, err := rlp.EncodeToBytes(hashes)
+	if err != nil {
+		log.Crit("Failed to encode reorg whitelist", "err", err)
+	}
+	if err := db.Put(reorgWhitelistKey, data); err != nil {
+		log.Crit("Failed to store reorg whitelist", "err", err)
+	}
+}
+
+// DeleteReorgWhitelist deletes the stored artificial finality reorg whitelist.
+func DeleteReorgWhitelist(db ethdb.KeyValueWriter) {
+	if err := db.Delete(reorgWhitelistKey); err != nil {
+		log.Crit("Failed to delete reorg whitelist", "err", err)
+	}
+}
+
+// ReadArtificialFinalityEnabled retrieves the artificial finality status last
+// chosen by the operator, or nil if none was stored.
+func ReadArtificialFinalityEnabled(db ethdb.KeyValueReader) *bool {
+	data, _ := db.Get(artificialFinalityEnabledKey)
+	if len(data) != 1 {
+		return nil
+	}
+	enabled := data[0] == 1
+	return &enabled
+}
+
+// WriteArtificialFinalityEnabled stores the artificial finality status chosen
+// by the operator.
+func WriteArtificialFinalityEnabled(db ethdb.KeyValueWriter, enabled bool) {
+	var flag byte
+	if enabled {
+		flag = 1
+	}
+	if err := db.Put(artificialFinalityEnabledKey, []byte{flag}); err != nil {
+		log.Crit("Failed to store artificial finality status", "err", err)
+	}
+}
@@ -94,6 +94,12 @@ var (
 	// snapSyncStatusFlagKey flags that status of snap sync.
 	snapSyncStatusFlagKey = []byte("SnapSyncStatus")
 
+	// reorgWhitelistKey tracks the reorg targets exempted from artificial finality.
+	reorgWhitelistKey = []byte("ArtificialFinalityReorgWhitelist")
+
+	// artificialFinalityEnabledKey tracks the artificial finality status last chosen by the operator.
+	artificialFinalityEnabledKey = []byte("ArtificialFinalityEnabled")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
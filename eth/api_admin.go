@@ -24,6 +24,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -152,6 +153,53 @@ func (api *AdminAPI) Ecbp1100(blockNr rpc.BlockNumber) (bool, error) {
 			api.eth.blockchain.CurrentBlock().Number), err
 }
 
+// WhitelistReorg exempts any chain containing the given block from artificial
+// finality checks. This is an emergency escape hatch for manual intervention.
+func (api *AdminAPI) WhitelistReorg(hash common.Hash) bool {
+	api.eth.blockchain.WhitelistReorgTarget(hash)
+	return true
+}
+
+// ClearReorgWhitelist removes all targets previously added with WhitelistReorg.
+func (api *AdminAPI) ClearReorgWhitelist() bool {
+	api.eth.blockchain.ClearReorgWhitelist()
+	return true
+}
+
+// SetArtificialFinalityResult is the result of admin_setArtificialFinality.
+type SetArtificialFinalityResult struct {
+	Enabled   bool   `json:"enabled"`
+	Challenge string `json:"challenge,omitempty"` // token confirming a pending disable
+}
+
+// SetArtificialFinality enables or disables artificial finality, persisting the
+// choice across restarts. If disabling requires confirmation, a call without a token only returns a
+// challenge, and the disable takes effect when called again with that token
+// shortly after.
+func (api *AdminAPI) SetArtificialFinality(enable bool, token *string) (*SetArtificialFinalityResult, error) {
+	var t string
+	if token != nil {
+		t = *token
+	}
+	challenge, err := api.eth.blockchain.SetArtificialFinalityConfirmed(enable, t)
+	if err != nil {
+		return nil, err
+	}
+	return &SetArtificialFinalityResult{Enabled: api.eth.blockchain.IsArtificialFinalityEnabled(), Challenge: challenge}, nil
+}
+
+// ClearArtificialFinalityNoDisable removes the --ecbp1100.nodisable override,
+// so artificial finality can be disabled again.
+func (api *AdminAPI) ClearArtificialFinalityNoDisable() bool {
+	api.eth.blockchain.ClearArtificialFinalityNoDisable()
+	return true
+}
+
+// ArtificialFinalityConfig returns the artificial finality settings in effect.
+func (api *AdminAPI) ArtificialFinalityConfig() core.AFConfig {
+	return api.eth.blockchain.ArtificialFinalityConfig()
+}
+
 // MaxPeers sets the maximum peer limit for the protocol manager and the p2p server.
 func (api *AdminAPI) MaxPeers(n int) (bool, error) {
 	api.eth.handler.maxPeers = n
@@ -18,12 +18,14 @@ package eth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -437,3 +439,121 @@ func (api *DebugAPI) GetTrieFlushInterval() (string, error) {
 	}
 	return api.eth.blockchain.GetTrieFlushInterval().String(), nil
 }
+
+// MESSDecisionResult is the result of debug_messDecision.
+type MESSDecisionResult struct {
+	Reorg                bool           `json:"reorg"`
+	Accepted             bool           `json:"accepted"`
+	Reason               string         `json:"reason,omitempty"`
+	CommonAncestor       common.Hash    `json:"commonAncestor"`
+	CommonAncestorNumber hexutil.Uint64 `json:"commonAncestorNumber"`
+	Current              common.Hash    `json:"current"`
+	CurrentNumber        hexutil.Uint64 `json:"currentNumber"`
+	Age                  hexutil.Uint64 `json:"age"`
+	CurveValue           *hexutil.Big   `json:"curveValue,omitempty"`
+	CurveDenominator     *hexutil.Big   `json:"curveDenominator,omitempty"`
+	LocalSubchainTD      *hexutil.Big   `json:"localSubchainTD,omitempty"`
+	ProposedSubchainTD   *hexutil.Big   `json:"proposedSubchainTD,omitempty"`
+}
+
+// MESSDecision evaluates whether ECBP1100 (MESS) would allow a reorg from the
+// current head to the given stored block, returning the operands of the decision.
+func (api *DebugAPI) MESSDecision(hash common.Hash) (*MESSDecisionResult, error) {
+	d, err := api.eth.blockchain.MESSDecision(hash)
+	if err != nil {
+		return nil, err
+	}
+	res := &MESSDecisionResult{
+		Reorg:                d.Reorg,
+		Accepted:             d.Rejected == nil,
+		CommonAncestor:       d.CommonAncestor.Hash(),
+		CommonAncestorNumber: hexutil.Uint64(d.CommonAncestor.Number.Uint64()),
+		Current:              d.Current.Hash(),
+		CurrentNumber:        hexutil.Uint64(d.Current.Number.Uint64()),
+		Age:                  hexutil.Uint64(d.Age),
+	}
+	if d.Rejected != nil {
+		res.Reason = d.Rejected.Error()
+	}
+	if d.Reorg {
+		res.CurveValue = (*hexutil.Big)(d.CurveValue)
+		res.CurveDenominator = (*hexutil.Big)(d.CurveDenominator)
+		res.LocalSubchainTD = (*hexutil.Big)(d.LocalSubchainTD)
+		res.ProposedSubchainTD = (*hexutil.Big)(d.ProposedSubchainTD)
+	}
+	return res, nil
+}
+
+// TraceAFDecision traces the ECBP1100 (MESS) decision for a reorg from the
+// stored block current to the stored block proposed, listing the blocks of
+// both segments along with the sums and operands of the comparison.
+func (api *DebugAPI) TraceAFDecision(current, proposed common.Hash) (json.RawMessage, error) {
+	return api.eth.blockchain.TraceAFDecision(current, proposed)
+}
+
+// AFCurveResult is the decision of a candidate ECBP1100 (MESS) curve, as
+// returned by debug_afCurveComparison.
+type AFCurveResult struct {
+	Curve    string  `json:"curve"`
+	Accepted bool    `json:"accepted"`
+	Margin   float64 `json:"margin"`
+}
+
+// AfCurveComparison evaluates whether each candidate ECBP1100 (MESS) curve
+// would allow a reorg from the current head to the given stored block.
+func (api *DebugAPI) AfCurveComparison(hash common.Hash) ([]AFCurveResult, error) {
+	evals, err := api.eth.blockchain.MESSCurveComparison(hash)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]AFCurveResult, len(evals))
+	for i, eval := range evals {
+		res[i] = AFCurveResult{Curve: eval.Curve, Accepted: eval.Accepted, Margin: eval.Margin}
+	}
+	return res, nil
+}
+
+// ReorgDecisionResult is a reorg allowed by artificial finality, as returned
+// by debug_lastAcceptedReorg.
+type ReorgDecisionResult struct {
+	CommonAncestor       common.Hash    `json:"commonAncestor"`
+	CommonAncestorNumber hexutil.Uint64 `json:"commonAncestorNumber"`
+	Current              common.Hash    `json:"current"`
+	CurrentNumber        hexutil.Uint64 `json:"currentNumber"`
+	Proposed             common.Hash    `json:"proposed"`
+	ProposedNumber       hexutil.Uint64 `json:"proposedNumber"`
+	Margin               float64        `json:"margin"`
+	Time                 hexutil.Uint64 `json:"time"`
+}
+
+// LastAcceptedReorg returns the most recent reorg allowed after evaluation by
+// artificial finality, or nil if there has been none since startup.
+func (api *DebugAPI) LastAcceptedReorg() *ReorgDecisionResult {
+	d, ok := api.eth.blockchain.LastAcceptedReorg()
+	if !ok {
+		return nil
+	}
+	return &ReorgDecisionResult{
+		CommonAncestor:       d.CommonAncestor.Hash(),
+		CommonAncestorNumber: hexutil.Uint64(d.CommonAncestor.Number.Uint64()),
+		Current:              d.Current.Hash(),
+		CurrentNumber:        hexutil.Uint64(d.Current.Number.Uint64()),
+		Proposed:             d.Proposed.Hash(),
+		ProposedNumber:       hexutil.Uint64(d.Proposed.Number.Uint64()),
+		Margin:               d.Margin,
+		Time:                 hexutil.Uint64(d.Time.Unix()),
+	}
+}
+
+// AfTimings returns the time taken by artificial finality decisions, overall
+// and by the depth of the current segment. Values are zero if metrics are
+// disabled.
+func (api *DebugAPI) AfTimings() []core.AFTiming {
+	return api.eth.blockchain.ArtificialFinalityTimings()
+}
+
+// ReorgRejectionStats returns statistics on the reorgs most recently
+// disallowed by artificial finality.
+func (api *DebugAPI) ReorgRejectionStats() core.RejectionStats {
+	return api.eth.blockchain.ReorgRejectionStats()
+}
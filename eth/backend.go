@@ -261,6 +261,13 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		if *config.ECBP1100NoDisable {
 			eth.blockchain.ArtificialFinalityNoDisable(1)
 		}
+	} else {
+		// Without an explicit config, pick up the status the operator chose before the restart.
+		eth.blockchain.RestoreArtificialFinality()
+	}
+	eth.blockchain.SetArtificialFinalityDisableConfirmation(config.ECBP1100ConfirmDisable)
+	if config.ECBP1100RejectionLogInterval != nil {
+		eth.blockchain.SetArtificialFinalityRejectionLogInterval(*config.ECBP1100RejectionLogInterval)
 	}
 
 	if config.BlobPool.Datadir != "" {
@@ -298,6 +305,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		EventMux:       eth.eventMux,
 		Checkpoint:     checkpoint,
 		RequiredBlocks: config.RequiredBlocks,
+		AFControlFile:  config.ECBP1100ControlFile,
 	}); err != nil {
 		return nil, err
 	}
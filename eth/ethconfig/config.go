@@ -232,8 +232,23 @@ type Config struct {
 
 	// ECBP1100NoDisable overrides
 	// When this value is *true, ECBP100 will not (ever) be disabled; when *false, it will never be enabled.
+	// When set, the artificial finality status last chosen by the operator is not restored at startup.
 	ECBP1100NoDisable *bool `toml:",omitempty"`
 
+	// ECBP1100ControlFile is the path of a file polled for "enable" or "disable"
+	// commands toggling artificial finality, as a fallback to the RPC.
+	// It cannot disable artificial finality if ECBP1100NoDisable is set.
+	ECBP1100ControlFile string `toml:",omitempty"`
+
+	// ECBP1100ConfirmDisable requires disabling artificial finality through the
+	// admin_setArtificialFinality RPC to be confirmed by a second call.
+	ECBP1100ConfirmDisable bool `toml:",omitempty"`
+
+	// ECBP1100RejectionLogInterval is the minimum interval between repeated
+	// warnings about reorgs with the same common ancestor disallowed by
+	// artificial finality. Zero logs every warning; nil keeps the default.
+	ECBP1100RejectionLogInterval *time.Duration `toml:",omitempty"`
+
 	// OverrideShanghai (TODO: remove after the fork)
 	OverrideShanghai *uint64 `toml:",omitempty"`
 
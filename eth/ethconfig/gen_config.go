@@ -19,60 +19,63 @@ import (
 // MarshalTOML marshals as TOML.
 func (c Config) MarshalTOML() (interface{}, error) {
 	type Config struct {
-		Genesis                    *genesisT.Genesis `toml:",omitempty"`
-		NetworkId                  uint64
-		ProtocolVersions           []uint
-		SyncMode                   downloader.SyncMode
-		EthDiscoveryURLs           []string
-		SnapDiscoveryURLs          []string
-		NoPruning                  bool
-		NoPrefetch                 bool
-		TxLookupLimit              uint64                 `toml:",omitempty"`
-		TransactionHistory         uint64                 `toml:",omitempty"`
-		StateHistory               uint64                 `toml:",omitempty"`
-		StateScheme                string                 `toml:",omitempty"`
-		RequiredBlocks             map[uint64]common.Hash `toml:"-"`
-		LightServ                  int                    `toml:",omitempty"`
-		LightIngress               int                    `toml:",omitempty"`
-		LightEgress                int                    `toml:",omitempty"`
-		LightPeers                 int                    `toml:",omitempty"`
-		LightNoPrune               bool                   `toml:",omitempty"`
-		LightNoSyncServe           bool                   `toml:",omitempty"`
-		SyncFromCheckpoint         bool                   `toml:",omitempty"`
-		UltraLightServers          []string               `toml:",omitempty"`
-		UltraLightFraction         int                    `toml:",omitempty"`
-		UltraLightOnlyAnnounce     bool                   `toml:",omitempty"`
-		SkipBcVersionCheck         bool                   `toml:"-"`
-		DatabaseHandles            int                    `toml:"-"`
-		DatabaseCache              int
-		DatabaseFreezer            string
-		DatabaseFreezerRemote      string
-		TrieCleanCache             int
-		TrieDirtyCache             int
-		TrieTimeout                time.Duration
-		SnapshotCache              int
-		Preimages                  bool
-		FilterLogCacheSize         int
-		Miner                      miner.Config
-		Ethash                     ethash.Config
-		TxPool                     legacypool.Config
-		BlobPool                   blobpool.Config
-		GPO                        gasprice.Config
-		EnablePreimageRecording    bool
-		DocRoot                    string `toml:"-"`
-		EWASMInterpreter           string
-		EVMInterpreter             string
-		RPCGasCap                  uint64
-		RPCEVMTimeout              time.Duration
-		RPCTxFeeCap                float64
-		Checkpoint                 *ctypes.TrustedCheckpoint      `toml:",omitempty"`
-		CheckpointOracle           *ctypes.CheckpointOracleConfig `toml:",omitempty"`
-		OverrideECBP1100           *uint64                        `toml:",omitempty"`
-		OverrideECBP1100Deactivate *uint64                        `toml:",omitempty"`
-		ECBP1100NoDisable          *bool                          `toml:",omitempty"`
-		OverrideShanghai           *uint64                        `toml:",omitempty"`
-		OverrideCancun             *uint64                        `toml:",omitempty"`
-		OverrideVerkle             *uint64                        `toml:",omitempty"`
+		Genesis                      *genesisT.Genesis `toml:",omitempty"`
+		NetworkId                    uint64
+		ProtocolVersions             []uint
+		SyncMode                     downloader.SyncMode
+		EthDiscoveryURLs             []string
+		SnapDiscoveryURLs            []string
+		NoPruning                    bool
+		NoPrefetch                   bool
+		TxLookupLimit                uint64                 `toml:",omitempty"`
+		TransactionHistory           uint64                 `toml:",omitempty"`
+		StateHistory                 uint64                 `toml:",omitempty"`
+		StateScheme                  string                 `toml:",omitempty"`
+		RequiredBlocks               map[uint64]common.Hash `toml:"-"`
+		LightServ                    int                    `toml:",omitempty"`
+		LightIngress                 int                    `toml:",omitempty"`
+		LightEgress                  int                    `toml:",omitempty"`
+		LightPeers                   int                    `toml:",omitempty"`
+		LightNoPrune                 bool                   `toml:",omitempty"`
+		LightNoSyncServe             bool                   `toml:",omitempty"`
+		SyncFromCheckpoint           bool                   `toml:",omitempty"`
+		UltraLightServers            []string               `toml:",omitempty"`
+		UltraLightFraction           int                    `toml:",omitempty"`
+		UltraLightOnlyAnnounce       bool                   `toml:",omitempty"`
+		SkipBcVersionCheck           bool                   `toml:"-"`
+		DatabaseHandles              int                    `toml:"-"`
+		DatabaseCache                int
+		DatabaseFreezer              string
+		DatabaseFreezerRemote        string
+		TrieCleanCache               int
+		TrieDirtyCache               int
+		TrieTimeout                  time.Duration
+		SnapshotCache                int
+		Preimages                    bool
+		FilterLogCacheSize           int
+		Miner                        miner.Config
+		Ethash                       ethash.Config
+		TxPool                       legacypool.Config
+		BlobPool                     blobpool.Config
+		GPO                          gasprice.Config
+		EnablePreimageRecording      bool
+		DocRoot                      string `toml:"-"`
+		EWASMInterpreter             string
+		EVMInterpreter               string
+		RPCGasCap                    uint64
+		RPCEVMTimeout                time.Duration
+		RPCTxFeeCap                  float64
+		Checkpoint                   *ctypes.TrustedCheckpoint      `toml:",omitempty"`
+		CheckpointOracle             *ctypes.CheckpointOracleConfig `toml:",omitempty"`
+		OverrideECBP1100             *uint64                        `toml:",omitempty"`
+		OverrideECBP1100Deactivate   *uint64                        `toml:",omitempty"`
+		ECBP1100NoDisable            *bool                          `toml:",omitempty"`
+		ECBP1100ControlFile          string                         `toml:",omitempty"`
+		ECBP1100ConfirmDisable       bool                           `toml:",omitempty"`
+		ECBP1100RejectionLogInterval *time.Duration                 `toml:",omitempty"`
+		OverrideShanghai             *uint64                        `toml:",omitempty"`
+		OverrideCancun               *uint64                        `toml:",omitempty"`
+		OverrideVerkle               *uint64                        `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -126,6 +129,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.OverrideECBP1100 = c.OverrideECBP1100
 	enc.OverrideECBP1100Deactivate = c.OverrideECBP1100Deactivate
 	enc.ECBP1100NoDisable = c.ECBP1100NoDisable
+	enc.ECBP1100ControlFile = c.ECBP1100ControlFile
+	enc.ECBP1100ConfirmDisable = c.ECBP1100ConfirmDisable
+	enc.ECBP1100RejectionLogInterval = c.ECBP1100RejectionLogInterval
 	enc.OverrideShanghai = c.OverrideShanghai
 	enc.OverrideCancun = c.OverrideCancun
 	enc.OverrideVerkle = c.OverrideVerkle
@@ -135,60 +141,63 @@ func (c Config) MarshalTOML() (interface{}, error) {
 // UnmarshalTOML unmarshals from TOML.
 func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	type Config struct {
-		Genesis                    *genesisT.Genesis `toml:",omitempty"`
-		NetworkId                  *uint64
-		ProtocolVersions           []uint
-		SyncMode                   *downloader.SyncMode
-		EthDiscoveryURLs           []string
-		SnapDiscoveryURLs          []string
-		NoPruning                  *bool
-		NoPrefetch                 *bool
-		TxLookupLimit              *uint64                `toml:",omitempty"`
-		TransactionHistory         *uint64                `toml:",omitempty"`
-		StateHistory               *uint64                `toml:",omitempty"`
-		StateScheme                *string                `toml:",omitempty"`
-		RequiredBlocks             map[uint64]common.Hash `toml:"-"`
-		LightServ                  *int                   `toml:",omitempty"`
-		LightIngress               *int                   `toml:",omitempty"`
-		LightEgress                *int                   `toml:",omitempty"`
-		LightPeers                 *int                   `toml:",omitempty"`
-		LightNoPrune               *bool                  `toml:",omitempty"`
-		LightNoSyncServe           *bool                  `toml:",omitempty"`
-		SyncFromCheckpoint         *bool                  `toml:",omitempty"`
-		UltraLightServers          []string               `toml:",omitempty"`
-		UltraLightFraction         *int                   `toml:",omitempty"`
-		UltraLightOnlyAnnounce     *bool                  `toml:",omitempty"`
-		SkipBcVersionCheck         *bool                  `toml:"-"`
-		DatabaseHandles            *int                   `toml:"-"`
-		DatabaseCache              *int
-		DatabaseFreezer            *string
-		DatabaseFreezerRemote      *string
-		TrieCleanCache             *int
-		TrieDirtyCache             *int
-		TrieTimeout                *time.Duration
-		SnapshotCache              *int
-		Preimages                  *bool
-		FilterLogCacheSize         *int
-		Miner                      *miner.Config
-		Ethash                     *ethash.Config
-		TxPool                     *legacypool.Config
-		BlobPool                   *blobpool.Config
-		GPO                        *gasprice.Config
-		EnablePreimageRecording    *bool
-		DocRoot                    *string `toml:"-"`
-		EWASMInterpreter           *string
-		EVMInterpreter             *string
-		RPCGasCap                  *uint64
-		RPCEVMTimeout              *time.Duration
-		RPCTxFeeCap                *float64
-		Checkpoint                 *ctypes.TrustedCheckpoint      `toml:",omitempty"`
-		CheckpointOracle           *ctypes.CheckpointOracleConfig `toml:",omitempty"`
-		OverrideECBP1100           *uint64                        `toml:",omitempty"`
-		OverrideECBP1100Deactivate *uint64                        `toml:",omitempty"`
-		ECBP1100NoDisable          *bool                          `toml:",omitempty"`
-		OverrideShanghai           *uint64                        `toml:",omitempty"`
-		OverrideCancun             *uint64                        `toml:",omitempty"`
-		OverrideVerkle             *uint64                        `toml:",omitempty"`
+		Genesis                      *genesisT.Genesis `toml:",omitempty"`
+		NetworkId                    *uint64
+		ProtocolVersions             []uint
+		SyncMode                     *downloader.SyncMode
+		EthDiscoveryURLs             []string
+		SnapDiscoveryURLs            []string
+		NoPruning                    *bool
+		NoPrefetch                   *bool
+		TxLookupLimit                *uint64                `toml:",omitempty"`
+		TransactionHistory           *uint64                `toml:",omitempty"`
+		StateHistory                 *uint64                `toml:",omitempty"`
+		StateScheme                  *string                `toml:",omitempty"`
+		RequiredBlocks               map[uint64]common.Hash `toml:"-"`
+		LightServ                    *int                   `toml:",omitempty"`
+		LightIngress                 *int                   `toml:",omitempty"`
+		LightEgress                  *int                   `toml:",omitempty"`
+		LightPeers                   *int                   `toml:",omitempty"`
+		LightNoPrune                 *bool                  `toml:",omitempty"`
+		LightNoSyncServe             *bool                  `toml:",omitempty"`
+		SyncFromCheckpoint           *bool                  `toml:",omitempty"`
+		UltraLightServers            []string               `toml:",omitempty"`
+		UltraLightFraction           *int                   `toml:",omitempty"`
+		UltraLightOnlyAnnounce       *bool                  `toml:",omitempty"`
+		SkipBcVersionCheck           *bool                  `toml:"-"`
+		DatabaseHandles              *int                   `toml:"-"`
+		DatabaseCache                *int
+		DatabaseFreezer              *string
+		DatabaseFreezerRemote        *string
+		TrieCleanCache               *int
+		TrieDirtyCache               *int
+		TrieTimeout                  *time.Duration
+		SnapshotCache                *int
+		Preimages                    *bool
+		FilterLogCacheSize           *int
+		Miner                        *miner.Config
+		Ethash                       *ethash.Config
+		TxPool                       *legacypool.Config
+		BlobPool                     *blobpool.Config
+		GPO                          *gasprice.Config
+		EnablePreimageRecording      *bool
+		DocRoot                      *string `toml:"-"`
+		EWASMInterpreter             *string
+		EVMInterpreter               *string
+		RPCGasCap                    *uint64
+		RPCEVMTimeout                *time.Duration
+		RPCTxFeeCap                  *float64
+		Checkpoint                   *ctypes.TrustedCheckpoint      `toml:",omitempty"`
+		CheckpointOracle             *ctypes.CheckpointOracleConfig `toml:",omitempty"`
+		OverrideECBP1100             *uint64                        `toml:",omitempty"`
+		OverrideECBP1100Deactivate   *uint64                        `toml:",omitempty"`
+		ECBP1100NoDisable            *bool                          `toml:",omitempty"`
+		ECBP1100ControlFile          *string                        `toml:",omitempty"`
+		ECBP1100ConfirmDisable       *bool                          `toml:",omitempty"`
+		ECBP1100RejectionLogInterval *time.Duration                 `toml:",omitempty"`
+		OverrideShanghai             *uint64                        `toml:",omitempty"`
+		OverrideCancun               *uint64                        `toml:",omitempty"`
+		OverrideVerkle               *uint64                        `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -347,6 +356,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.ECBP1100NoDisable != nil {
 		c.ECBP1100NoDisable = dec.ECBP1100NoDisable
 	}
+	if dec.ECBP1100ControlFile != nil {
+		c.ECBP1100ControlFile = *dec.ECBP1100ControlFile
+	}
+	if dec.ECBP1100ConfirmDisable != nil {
+		c.ECBP1100ConfirmDisable = *dec.ECBP1100ConfirmDisable
+	}
+	if dec.ECBP1100RejectionLogInterval != nil {
+		c.ECBP1100RejectionLogInterval = dec.ECBP1100RejectionLogInterval
+	}
 	if dec.OverrideShanghai != nil {
 		c.OverrideShanghai = dec.OverrideShanghai
 	}
@@ -96,6 +96,7 @@ type handlerConfig struct {
 	EventMux       *event.TypeMux            // Legacy event mux, deprecate for `feed`
 	Checkpoint     *ctypes.TrustedCheckpoint // Hard coded checkpoint for sync challenges
 	RequiredBlocks map[uint64]common.Hash    // Hard coded map of required block hashes for sync challenges
+	AFControlFile  string                    // Path of the artificial finality control file, if any
 }
 
 type handler struct {
@@ -126,6 +127,8 @@ type handler struct {
 
 	requiredBlocks map[uint64]common.Hash
 
+	afControlFile string // Path polled for artificial finality commands, if set
+
 	// channels for fetcher, syncer, txsyncLoop
 	quitSync chan struct{}
 
@@ -152,6 +155,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		peers:          newPeerSet(),
 		merger:         config.Merger,
 		requiredBlocks: config.RequiredBlocks,
+		afControlFile:  config.AFControlFile,
 		quitSync:       make(chan struct{}),
 		handlerDoneCh:  make(chan struct{}),
 		handlerStartCh: make(chan struct{}),
@@ -607,6 +611,12 @@ func (h *handler) Start(maxPeers int) {
 	h.wg.Add(1)
 	go h.artificialFinalitySafetyLoop()
 
+	// start artificial finality control file loop
+	if h.afControlFile != "" {
+		h.wg.Add(1)
+		go h.artificialFinalityControlFileLoop()
+	}
+
 	// start peer handler tracker
 	h.wg.Add(1)
 	go h.protoTracker()
@@ -19,6 +19,8 @@ package eth
 import (
 	"errors"
 	"math/big"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -44,6 +46,10 @@ var (
 	// If the head is found to be stale across this interval, artificial finality features are disabled.
 	// This prevents an abandoned victim of an eclipse attack from being forever destitute.
 	artificialFinalitySafetyInterval = time.Second * time.Duration(30*vars.DurationLimit.Uint64())
+
+	// artificialFinalityControlFileInterval defines the interval at which the artificial finality
+	// control file is polled for operator commands.
+	artificialFinalityControlFileInterval = 10 * time.Second
 )
 
 // artificialFinalitySafetyLoop compares our local head across timer intervals.
@@ -75,6 +81,54 @@ func (h *handler) artificialFinalitySafetyLoop() {
 	}
 }
 
+// artificialFinalityControlFileLoop polls the artificial finality control file,
+// enabling or disabling artificial finality features when its command changes.
+// It serves as a fallback control channel for operators without RPC access.
+func (h *handler) artificialFinalityControlFileLoop() {
+	defer h.wg.Done()
+
+	t := time.NewTicker(artificialFinalityControlFileInterval)
+	defer t.Stop()
+
+	var last string
+	for {
+		h.applyArtificialFinalityControlFile(&last)
+		select {
+		case <-t.C:
+		case <-h.quitSync:
+			return
+		}
+	}
+}
+
+// applyArtificialFinalityControlFile reads the artificial finality control file
+// and, if its command differs from last, applies it and updates last. A missing
+// or empty file holds no command. The file toggles artificial finality like the
+// automatic toggles of the sync loops do, without persisting the status, and
+// cannot disable it if it is forced on with the nodisable override.
+func (h *handler) applyArtificialFinalityControlFile(last *string) {
+	var command string
+	if data, err := os.ReadFile(h.afControlFile); err == nil {
+		command = strings.ToLower(strings.TrimSpace(string(data)))
+	} else if !os.IsNotExist(err) {
+		log.Warn("Failed to read artificial finality control file", "path", h.afControlFile, "err", err)
+		return
+	}
+	if command == *last {
+		return
+	}
+	*last = command
+
+	switch command {
+	case "enable", "disable":
+		log.Info("Artificial finality control file changed", "path", h.afControlFile, "command", command)
+		h.chain.EnableArtificialFinality(command == "enable", "reason", "control file", "path", h.afControlFile)
+	case "":
+	default:
+		log.Warn("Unknown artificial finality control file command", "path", h.afControlFile, "command", command)
+	}
+}
+
 // syncTransactions starts sending all currently pending transactions to the given peer.
 func (h *handler) syncTransactions(p *eth.Peer) {
 	var hashes []common.Hash
@@ -18,6 +18,8 @@ package eth
 
 import (
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -401,3 +403,79 @@ func TestArtificialFinalitySafetyLoopTimeComparison(t *testing.T) {
 		t.Fatal("bad unit logic!")
 	}
 }
+
+// TestArtificialFinalityControlFile tests that commands written to the control
+// file toggle artificial finality, subject to the nodisable override.
+func TestArtificialFinalityControlFile(t *testing.T) {
+	h := newTestHandler()
+	defer h.close()
+
+	h.handler.afControlFile = filepath.Join(t.TempDir(), "af")
+	write := func(command string) {
+		if err := os.WriteFile(h.handler.afControlFile, []byte(command), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var last string
+	h.handler.applyArtificialFinalityControlFile(&last) // missing file is ignored
+	if h.chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality enabled without control file")
+	}
+	write("enable\n")
+	h.handler.applyArtificialFinalityControlFile(&last)
+	if !h.chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality not enabled by control file")
+	}
+	write("bogus")
+	h.handler.applyArtificialFinalityControlFile(&last)
+	if !h.chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality toggled by unknown command")
+	}
+	write("DISABLE")
+	h.handler.applyArtificialFinalityControlFile(&last)
+	if h.chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality not disabled by control file")
+	}
+	// Once forced on, the control file can't disable artificial finality.
+	write("enable")
+	h.handler.applyArtificialFinalityControlFile(&last)
+	h.chain.ArtificialFinalityNoDisable(1)
+	write("disable")
+	h.handler.applyArtificialFinalityControlFile(&last)
+	if !h.chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality disabled by control file despite nodisable")
+	}
+}
+
+// TestArtificialFinalityControlFileSync tests that the control file toggles
+// artificial finality without persisting it, so that the sync loop safety
+// toggles still apply and removing the file leaves the status alone.
+func TestArtificialFinalityControlFileSync(t *testing.T) {
+	h := newTestHandler()
+	defer h.close()
+
+	h.handler.afControlFile = filepath.Join(t.TempDir(), "af")
+	if err := os.WriteFile(h.handler.afControlFile, []byte("enable"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var last string
+	h.handler.applyArtificialFinalityControlFile(&last)
+	if !h.chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality not enabled by control file")
+	}
+	if enabled := rawdb.ReadArtificialFinalityEnabled(h.db); enabled != nil {
+		t.Fatalf("control file command persisted: %v", *enabled)
+	}
+	if err := os.Remove(h.handler.afControlFile); err != nil {
+		t.Fatal(err)
+	}
+	h.handler.applyArtificialFinalityControlFile(&last)
+	if !h.chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality toggled by removing the control file")
+	}
+	// Without peers, the sync loop disables artificial finality regardless.
+	h.handler.chainSync.nextSyncOp()
+	if h.chain.IsArtificialFinalityEnabled() {
+		t.Fatal("artificial finality not disabled by the low peers floor")
+	}
+}
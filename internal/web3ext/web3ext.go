@@ -164,6 +164,32 @@ web3._extend({
 			call: 'admin_ecbp1100',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'whitelistReorg',
+			call: 'admin_whitelistReorg',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'clearReorgWhitelist',
+			call: 'admin_clearReorgWhitelist',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'clearArtificialFinalityNoDisable',
+			call: 'admin_clearArtificialFinalityNoDisable',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'artificialFinalityConfig',
+			call: 'admin_artificialFinalityConfig',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'setArtificialFinality',
+			call: 'admin_setArtificialFinality',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -512,6 +538,33 @@ web3._extend({
 			call: 'debug_getTrieFlushInterval',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'messDecision',
+			call: 'debug_messDecision',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'afCurveComparison',
+			call: 'debug_afCurveComparison',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'lastAcceptedReorg',
+			call: 'debug_lastAcceptedReorg'
+		}),
+		new web3._extend.Method({
+			name: 'afTimings',
+			call: 'debug_afTimings'
+		}),
+		new web3._extend.Method({
+			name: 'reorgRejectionStats',
+			call: 'debug_reorgRejectionStats'
+		}),
+		new web3._extend.Method({
+			name: 'traceAFDecision',
+			call: 'debug_traceAFDecision',
+			params: 2
+		}),
 	],
 	properties: []
 });
@@ -187,6 +187,11 @@ type CoreGethChainConfig struct {
 	ECIP1099FBlock           *big.Int `json:"ecip1099FBlock,omitempty"`                 // ECIP1099 etchash HF block
 	ECBP1100FBlock           *big.Int `json:"ecbp1100FBlock,omitempty"`                 // ECBP1100:MESS artificial finality
 	ECBP1100DeactivateFBlock *big.Int `json:"ecbp1100DeactivateFBlockFBlock,omitempty"` // Deactivate ECBP1100:MESS artificial finality
+	ECBP1100MaxAge           *uint64  `json:"ecbp1100MaxAge,omitempty"`                 // ECBP1100:MESS maximum reorg age in seconds, older reorgs are rejected outright
+
+	ECBP1100TieBreak *ctypes.ECBP1100TieBreakT `json:"ecbp1100TieBreak,omitempty"` // ECBP1100:MESS outcome when the proposed segment exactly meets the curve
+	AFWarnMargin     *float64                  `json:"afWarnMargin,omitempty"`     // ECBP1100:MESS margin below which accepted reorgs are logged with their operands and counted (default 1.2)
+	MESSTipGrace     *uint64                   `json:"messTipGrace,omitempty"`     // ECBP1100:MESS exempts reorgs forking at most this many blocks below the head
 
 	// EIP-2315: Simple Subroutines
 	// https://eips.ethereum.org/EIPS/eip-2315
@@ -436,6 +436,42 @@ func (c *CoreGethChainConfig) SetECBP1100DeactivateTransition(n *uint64) error {
 	return nil
 }
 
+func (c *CoreGethChainConfig) GetECBP1100MaxAge() *uint64 {
+	return c.ECBP1100MaxAge
+}
+
+func (c *CoreGethChainConfig) SetECBP1100MaxAge(n *uint64) error {
+	c.ECBP1100MaxAge = n
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetECBP1100TieBreak() *ctypes.ECBP1100TieBreakT {
+	return c.ECBP1100TieBreak
+}
+
+func (c *CoreGethChainConfig) SetECBP1100TieBreak(t *ctypes.ECBP1100TieBreakT) error {
+	c.ECBP1100TieBreak = t
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetAFWarnMargin() *float64 {
+	return c.AFWarnMargin
+}
+
+func (c *CoreGethChainConfig) SetAFWarnMargin(m *float64) error {
+	c.AFWarnMargin = m
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetMESSTipGrace() *uint64 {
+	return c.MESSTipGrace
+}
+
+func (c *CoreGethChainConfig) SetMESSTipGrace(n *uint64) error {
+	c.MESSTipGrace = n
+	return nil
+}
+
 func (c *CoreGethChainConfig) GetEIP2315Transition() *uint64 {
 	return bigNewU64(c.EIP2315FBlock)
 }
@@ -144,6 +144,14 @@ type ProtocolSpecifier interface {
 	SetECBP1100Transition(n *uint64) error
 	GetECBP1100DeactivateTransition() *uint64
 	SetECBP1100DeactivateTransition(n *uint64) error
+	GetECBP1100MaxAge() *uint64 // seconds
+	SetECBP1100MaxAge(n *uint64) error
+	GetECBP1100TieBreak() *ECBP1100TieBreakT
+	SetECBP1100TieBreak(t *ECBP1100TieBreakT) error
+	GetAFWarnMargin() *float64 // MESS margin below which accepted reorgs are reported as near-threshold
+	SetAFWarnMargin(m *float64) error
+	GetMESSTipGrace() *uint64 // blocks below the head within which a common ancestor exempts a reorg from MESS
+	SetMESSTipGrace(n *uint64) error
 
 	GetEIP2315Transition() *uint64
 	SetEIP2315Transition(n *uint64) error
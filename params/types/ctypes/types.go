@@ -320,6 +320,49 @@ func (b BlockSealingT) String() string {
 	}
 }
 
+// ECBP1100TieBreakT selects the ECBP1100 (MESS) outcome when the proposed
+// chain segment exactly meets the curve requirement.
+type ECBP1100TieBreakT int
+
+const (
+	ECBP1100TieBreak_FavorProposed  ECBP1100TieBreakT = iota // allow the reorg (default)
+	ECBP1100TieBreak_FavorIncumbent                          // reject the reorg
+)
+
+func (t ECBP1100TieBreakT) String() string {
+	switch t {
+	case ECBP1100TieBreak_FavorProposed:
+		return "favorProposed"
+	case ECBP1100TieBreak_FavorIncumbent:
+		return "favorIncumbent"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ECBP1100TieBreakT) MarshalText() ([]byte, error) {
+	switch t {
+	case ECBP1100TieBreak_FavorProposed, ECBP1100TieBreak_FavorIncumbent:
+		return []byte(t.String()), nil
+	default:
+		return nil, fmt.Errorf("unknown ECBP1100 tie break %d", int(t))
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ECBP1100TieBreakT) UnmarshalText(input []byte) error {
+	switch string(input) {
+	case "favorProposed":
+		*t = ECBP1100TieBreak_FavorProposed
+	case "favorIncumbent":
+		*t = ECBP1100TieBreak_FavorIncumbent
+	default:
+		return fmt.Errorf("unknown ECBP1100 tie break %q", input)
+	}
+	return nil
+}
+
 // TrustedCheckpoint represents a set of post-processed trie roots (CHT and
 // BloomTrie) associated with the appropriate section index and head hash. It is
 // used to start light syncing from this checkpoint and avoid downloading the
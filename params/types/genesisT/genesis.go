@@ -883,6 +883,38 @@ func (g *Genesis) SetECBP1100DeactivateTransition(n *uint64) error {
 	return g.Config.SetECBP1100DeactivateTransition(n)
 }
 
+func (g *Genesis) GetECBP1100MaxAge() *uint64 {
+	return g.Config.GetECBP1100MaxAge()
+}
+
+func (g *Genesis) SetECBP1100MaxAge(n *uint64) error {
+	return g.Config.SetECBP1100MaxAge(n)
+}
+
+func (g *Genesis) GetECBP1100TieBreak() *ctypes.ECBP1100TieBreakT {
+	return g.Config.GetECBP1100TieBreak()
+}
+
+func (g *Genesis) SetECBP1100TieBreak(t *ctypes.ECBP1100TieBreakT) error {
+	return g.Config.SetECBP1100TieBreak(t)
+}
+
+func (g *Genesis) GetAFWarnMargin() *float64 {
+	return g.Config.GetAFWarnMargin()
+}
+
+func (g *Genesis) SetAFWarnMargin(m *float64) error {
+	return g.Config.SetAFWarnMargin(m)
+}
+
+func (g *Genesis) GetMESSTipGrace() *uint64 {
+	return g.Config.GetMESSTipGrace()
+}
+
+func (g *Genesis) SetMESSTipGrace(n *uint64) error {
+	return g.Config.SetMESSTipGrace(n)
+}
+
 func (g *Genesis) IsEnabled(fn func() *uint64, n *big.Int) bool {
 	return g.Config.IsEnabled(fn, n)
 }
@@ -101,6 +101,10 @@ type ChainConfig struct {
 	// Cache types for use with testing, but will not show up in config API.
 	ecbp1100Transition           *big.Int
 	ecbp1100DeactivateTransition *big.Int
+	ecbp1100MaxAge               *uint64
+	ecbp1100TieBreak             *ctypes.ECBP1100TieBreakT
+	messTipGrace                 *uint64
+	afWarnMargin                 *float64
 
 	Lyra2NonceTransitionBlock *big.Int `json:"lyra2NonceTransitionBlock,omitempty"`
 }
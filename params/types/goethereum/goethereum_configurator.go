@@ -461,6 +461,42 @@ func (c *ChainConfig) SetECBP1100DeactivateTransition(n *uint64) error {
 	return nil
 }
 
+func (c *ChainConfig) GetECBP1100MaxAge() *uint64 {
+	return c.ecbp1100MaxAge
+}
+
+func (c *ChainConfig) SetECBP1100MaxAge(n *uint64) error {
+	c.ecbp1100MaxAge = n
+	return nil
+}
+
+func (c *ChainConfig) GetECBP1100TieBreak() *ctypes.ECBP1100TieBreakT {
+	return c.ecbp1100TieBreak
+}
+
+func (c *ChainConfig) SetECBP1100TieBreak(t *ctypes.ECBP1100TieBreakT) error {
+	c.ecbp1100TieBreak = t
+	return nil
+}
+
+func (c *ChainConfig) GetAFWarnMargin() *float64 {
+	return c.afWarnMargin
+}
+
+func (c *ChainConfig) SetAFWarnMargin(m *float64) error {
+	c.afWarnMargin = m
+	return nil
+}
+
+func (c *ChainConfig) GetMESSTipGrace() *uint64 {
+	return c.messTipGrace
+}
+
+func (c *ChainConfig) SetMESSTipGrace(n *uint64) error {
+	c.messTipGrace = n
+	return nil
+}
+
 // GetEIP2315Transition implements EIP2537.
 // This logic is written but not configured for any Ethereum-supported networks, yet.
 func (c *ChainConfig) GetEIP2315Transition() *uint64 {
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"math/big"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -39,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/trie/triedb/hashdb"
@@ -190,15 +192,22 @@ func GetChainConfig(forkString string) (baseConfig ctypes.ChainConfigurator, eip
 
 // Subtests returns all valid subtests of the test.
 func (t *StateTest) Subtests(skipForks []*regexp.Regexp) []StateSubtest {
+	// Iterate the forks in order so the subtests are sorted by fork and index.
+	forks := make([]string, 0, len(t.json.Post))
+	for fork := range t.json.Post {
+		forks = append(forks, fork)
+	}
+	sort.Strings(forks)
+
 	var sub []StateSubtest
 outer:
-	for fork, pss := range t.json.Post {
+	for _, fork := range forks {
 		for _, skip := range skipForks {
 			if skip.MatchString(fork) {
 				continue outer
 			}
 		}
-		for i := range pss {
+		for i := range t.json.Post[fork] {
 			sub = append(sub, StateSubtest{fork, i})
 		}
 	}
@@ -229,11 +238,26 @@ func (t *StateTest) checkError(subtest StateSubtest, err error) error {
 
 // Run executes a specific subtest and verifies the post-state and logs
 func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config, snapshotter bool, scheme string, postCheck func(err error, snaps *snapshot.Tree, state *state.StateDB)) (result error) {
-	triedb, snaps, statedb, root, err := t.RunNoVerify(subtest, vmconfig, snapshotter, scheme)
+	return t.RunWithExecution(subtest, vmconfig, snapshotter, scheme, func(err error, snaps *snapshot.Tree, state *state.StateDB, _ StateTestExecution) {
+		postCheck(err, snaps, state)
+	})
+}
+
+// StateTestExecution is the outcome of executing the message of a subtest, as
+// its receipt would report it.
+type StateTestExecution struct {
+	GasUsed     uint64 // Gas used by the message, zero if it wasn't applied
+	BlobGasUsed uint64 // Blob gas of the message, zero if it wasn't applied or carries no blobs
+}
+
+// RunWithExecution is Run, additionally passing the outcome of executing the
+// message to the callback.
+func (t *StateTest) RunWithExecution(subtest StateSubtest, vmconfig vm.Config, snapshotter bool, scheme string, postCheck func(err error, snaps *snapshot.Tree, state *state.StateDB, exec StateTestExecution)) (result error) {
+	triedb, snaps, statedb, root, exec, err := t.runNoVerify(subtest, vmconfig, snapshotter, scheme)
 
 	// Invoke the callback at the end of function for further analysis.
 	defer func() {
-		postCheck(result, snaps, statedb)
+		postCheck(result, snaps, statedb, exec)
 
 		if triedb != nil {
 			triedb.Close()
@@ -261,7 +285,11 @@ func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config, snapshotter bo
 	if logs := rlpHash(statedb.Logs()); logs != common.Hash(post.Logs) {
 		return fmt.Errorf("post state logs hash mismatch: got %x, want %x", logs, post.Logs)
 	}
-	statedb, _ = state.New(root, statedb.Database(), snaps)
+	// Keep the committed state for the callback if it can't be reopened, so the
+	// post state root is still reported.
+	if reopened, err := state.New(root, statedb.Database(), snaps); err == nil {
+		statedb = reopened
+	}
 	return nil
 }
 
@@ -288,11 +316,20 @@ func (t *StateTest) RunNoVerifyWithPost(subtest StateSubtest, vmconfig vm.Config
 
 // RunNoVerify runs a specific subtest and returns the statedb and post-state root
 func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapshotter bool, scheme string) (*trie.Database, *snapshot.Tree, *state.StateDB, common.Hash, error) {
+	triedb, snaps, statedb, root, _, err := t.runNoVerify(subtest, vmconfig, snapshotter, scheme)
+	return triedb, snaps, statedb, root, err
+}
+
+// runNoVerify implements RunNoVerify, additionally returning the outcome of
+// executing the message.
+func (t *StateTest) runNoVerify(subtest StateSubtest, vmconfig vm.Config, snapshotter bool, scheme string) (*trie.Database, *snapshot.Tree, *state.StateDB, common.Hash, StateTestExecution, error) {
+	var exec StateTestExecution
 	config, eips, err := GetChainConfig(subtest.Fork)
 	if err != nil {
-		return nil, nil, nil, common.Hash{}, UnsupportedForkError{subtest.Fork}
+		return nil, nil, nil, common.Hash{}, exec, UnsupportedForkError{subtest.Fork}
 	}
-	vmconfig.ExtraEips = eips
+	// Enable the EIPs of the fork name on top of any requested by the caller.
+	vmconfig.ExtraEips = append(eips, vmconfig.ExtraEips...)
 
 	block := core.GenesisToBlock(t.genesis(config), nil)
 	triedb, snaps, statedb := MakePreState(rawdb.NewMemoryDatabase(), t.json.Pre.toGenesisAlloc(), snapshotter, scheme)
@@ -315,7 +352,7 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 	msg, err := t.json.Tx.toMessage(post, baseFee)
 	if err != nil {
 		triedb.Close()
-		return nil, nil, nil, common.Hash{}, err
+		return nil, nil, nil, common.Hash{}, exec, err
 	}
 
 	// Try to recover tx with current signer
@@ -324,12 +361,12 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 		err := ttx.UnmarshalBinary(post.TxBytes)
 		if err != nil {
 			triedb.Close()
-			return nil, nil, nil, common.Hash{}, err
+			return nil, nil, nil, common.Hash{}, exec, err
 		}
 
 		if _, err := types.Sender(types.LatestSigner(config), &ttx); err != nil {
 			triedb.Close()
-			return nil, nil, nil, common.Hash{}, err
+			return nil, nil, nil, common.Hash{}, exec, err
 		}
 	}
 
@@ -353,9 +390,12 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 	snapshot := statedb.Snapshot()
 	gaspool := new(core.GasPool)
 	gaspool.AddGas(block.GasLimit())
-	_, err = core.ApplyMessage(evm, msg, gaspool)
+	res, err := core.ApplyMessage(evm, msg, gaspool)
 	if err != nil {
 		statedb.RevertToSnapshot(snapshot)
+	} else {
+		exec.GasUsed = res.UsedGas
+		exec.BlobGasUsed = uint64(len(msg.BlobHashes)) * vars.BlobTxBlobGasPerBlob
 	}
 	// Add 0-value mining reward. This only makes a difference in the cases
 	// where
@@ -366,7 +406,7 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 
 	// Commit state mutations into database.
 	root, _ := statedb.Commit(block.NumberU64(), config.IsEnabled(config.GetEIP161dTransition, block.Number()))
-	return triedb, snaps, statedb, root, err
+	return triedb, snaps, statedb, root, exec, err
 }
 
 func (t *StateTest) gasLimit(subtest StateSubtest) uint64 {
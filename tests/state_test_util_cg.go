@@ -27,6 +27,36 @@ func (p stPre) toGenesisAlloc() genesisT.GenesisAlloc {
 	return genesisAlloc
 }
 
+// PreState returns the accounts of the pre-state of the test.
+func (t *StateTest) PreState() genesisT.GenesisAlloc {
+	return t.json.Pre.toGenesisAlloc()
+}
+
+// MergePreState adds the accounts of alloc to the pre-state of the test. Accounts
+// defined by the test itself take precedence; their addresses are returned.
+func (t *StateTest) MergePreState(alloc genesisT.GenesisAlloc) (collisions []common.Address) {
+	if t.json.Pre == nil {
+		t.json.Pre = make(stPre, len(alloc))
+	}
+	for addr, acc := range alloc {
+		if _, ok := t.json.Pre[addr]; ok {
+			collisions = append(collisions, addr)
+			continue
+		}
+		balance := acc.Balance
+		if balance == nil {
+			balance = new(big.Int)
+		}
+		t.json.Pre[addr] = stPreAccount{
+			Code:    acc.Code,
+			Storage: acc.Storage,
+			Balance: balance,
+			Nonce:   acc.Nonce,
+		}
+	}
+	return collisions
+}
+
 //go:generate go run github.com/fjl/gencodec -type stPreAccount -field-override stPreAccountMarshaling -out gen_stpreaccount.go
 
 // stPreAccount is structurally equivalent to genesisT.GenesisAccount, but